@@ -0,0 +1,83 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// redSquare is an opaque red square in the middle of a transparent image.
+func redSquare() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 14, 14))
+	for y := 4; y < 10; y++ {
+		for x := 4; x < 10; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{255, 0, 0, 255})
+		}
+	}
+	return img
+}
+
+func TestFiltersHandleAlpha(t *testing.T) {
+	box := ConvolutionOptions{Kernel: [][]float64{{1, 1, 1}, {1, 1, 1}, {1, 1, 1}}}
+	sharpenKernel := ConvolutionOptions{Kernel: [][]float64{{0, -1, 0}, {-1, 5, -1}, {0, -1, 0}}}
+	tests := []struct {
+		name string
+		// smoothing filters blur alpha together with the colour, all others
+		// keep the alpha of every pixel
+		smoothing bool
+		filter    func(image.Image) image.Image
+	}{
+		{"average", true, ApplyAveragingFilter},
+		{"gaussian 3x3", true, ApplyGaussianBlur},
+		{"gaussian", true, func(m image.Image) image.Image { return gaussianBlur(m, 1.5, 0) }},
+		{"median", false, ApplyMedianFilter},
+		{"median circle", false, func(m image.Image) image.Image { return medianFilter(m, 7, medianCircle) }},
+		{"box kernel", false, func(m image.Image) image.Image { return convolve(m, box) }},
+		{"sharpen kernel", false, func(m image.Image) image.Image { return convolve(m, sharpenKernel) }},
+		{"unsharp mask", false, func(m image.Image) image.Image {
+			return sharpen(m, SharpenOptions{Method: sharpenUnsharp, Amount: 1, Radius: 2})
+		}},
+		{"bilateral", false, func(m image.Image) image.Image { return bilateralFilter(m, 2, 30) }},
+		{"kuwahara", false, func(m image.Image) image.Image { return kuwaharaFilter(m, 2) }},
+		{"diffusion", false, func(m image.Image) image.Image {
+			return anisotropicDiffusion(m, 10, 30, conductionQuadratic)
+		}},
+	}
+	for _, tt := range tests {
+		out := tt.filter(redSquare())
+		edge := color.NRGBAModel.Convert(out.At(4, 7)).(color.NRGBA)
+		if edge.R < 250 || edge.G > 5 || edge.B > 5 {
+			t.Errorf("%s: edge of the square turned %v", tt.name, edge)
+		}
+		if _, _, _, a := out.At(0, 0).RGBA(); a != 0 {
+			t.Errorf("%s: transparent corner got alpha %d", tt.name, a>>8)
+		}
+		if tt.smoothing {
+			if edge.A == 255 || edge.A == 0 {
+				t.Errorf("%s: edge alpha %d was not smoothed", tt.name, edge.A)
+			}
+		} else if edge.A != 255 {
+			t.Errorf("%s: edge alpha changed to %d", tt.name, edge.A)
+		}
+	}
+}
+
+func TestCannyKeepsAlpha(t *testing.T) {
+	src := redSquare()
+	out := canny(src, CannyOptions{Sigma: 1, Low: 20, High: 60, Thresholds: cannyManual}).(*image.NRGBA)
+	edges := 0
+	for y := 0; y < 14; y++ {
+		for x := 0; x < 14; x++ {
+			c := out.NRGBAAt(x, y)
+			if c.A != src.NRGBAAt(x, y).A {
+				t.Fatalf("alpha at (%d, %d) changed to %d", x, y, c.A)
+			}
+			if c.R == 255 && c.A == 255 {
+				edges++
+			}
+		}
+	}
+	if edges == 0 {
+		t.Error("no visible edge around the square")
+	}
+}
@@ -2,16 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
+	"sync"
 )
 
 // App struct
 type App struct {
-	ctx context.Context
+	ctx    context.Context
+	images *imageRegistry
+
+	mu        sync.Mutex
+	selection *selection
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	return &App{images: newImageRegistry()}
 }
 
 // startup is called when the app starts. The context is saved
@@ -19,3 +25,13 @@ func NewApp() *App {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 }
+
+// formatError lets bound methods return structured errors, anything else is
+// sent to the frontend as its message like wails does by default.
+func formatError(err error) any {
+	var saveErr *SaveError
+	if errors.As(err, &saveErr) {
+		return saveErr
+	}
+	return err.Error()
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// benchImage is a 1024x1024 gradient with some texture, big enough for the
+// tiles to be spread over all cores.
+func benchImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 1024, 1024))
+	for y := 0; y < 1024; y++ {
+		for x := 0; x < 1024; x++ {
+			v := 128 + 100*math.Sin(float64(x)/17)*math.Cos(float64(y)/23)
+			img.SetNRGBA(x, y, color.NRGBA{uint8(v), uint8(x / 4), uint8(y / 4), 255})
+		}
+	}
+	return img
+}
+
+// benchSerialParallel runs the filter with one worker and with GOMAXPROCS
+// workers, so go test -bench shows the speedup of the tiling.
+func benchSerialParallel(b *testing.B, filter func(image.Image) image.Image) {
+	img := benchImage()
+	defer func(n int) { maxWorkers = n }(maxWorkers)
+	for _, run := range []struct {
+		name    string
+		workers int
+	}{{"serial", 1}, {"parallel", 0}} {
+		b.Run(run.name, func(b *testing.B) {
+			maxWorkers = run.workers
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				filter(img)
+			}
+		})
+	}
+}
+
+func BenchmarkAverage(b *testing.B) { benchSerialParallel(b, ApplyAveragingFilter) }
+
+func BenchmarkMedian(b *testing.B) {
+	benchSerialParallel(b, func(m image.Image) image.Image { return medianFilter(m, 7, medianSquare) })
+}
+
+func BenchmarkGaussian(b *testing.B) {
+	benchSerialParallel(b, func(m image.Image) image.Image { return gaussianBlur(m, 3, 0) })
+}
+
+func BenchmarkConvolve(b *testing.B) {
+	opts := ConvolutionOptions{Kernel: [][]float64{{0, -1, 0}, {-1, 5, -1}, {0, -1, 0}}}
+	benchSerialParallel(b, func(m image.Image) image.Image { return convolve(m, opts) })
+}
+
+func BenchmarkSobel(b *testing.B) { benchSerialParallel(b, ApplySobelFilter) }
+
+func BenchmarkCanny(b *testing.B) {
+	benchSerialParallel(b, func(m image.Image) image.Image {
+		return canny(m, CannyOptions{Sigma: 1.4, Low: 50, High: 150, Thresholds: cannyManual})
+	})
+}
+
+func BenchmarkBilateral(b *testing.B) {
+	benchSerialParallel(b, func(m image.Image) image.Image { return bilateralFilter(m, 2, 25) })
+}
+
+func BenchmarkFrequencyFilter(b *testing.B) {
+	benchSerialParallel(b, func(m image.Image) image.Image {
+		return frequencyFilter(m, FrequencyFilterOptions{
+			Pass: passLow, Shape: shapeGaussian, Cutoff: 0.2, Channels: frequencyLuminance,
+		})
+	})
+}
+
+func BenchmarkOtsu(b *testing.B) { benchSerialParallel(b, binarizeOtsu) }
+
+func BenchmarkNiblack(b *testing.B) {
+	benchSerialParallel(b, func(m image.Image) image.Image { return binarizeNiblack(m, 15, -0.2) })
+}
+
+func BenchmarkBernsen(b *testing.B) {
+	benchSerialParallel(b, func(m image.Image) image.Image { return binarizeBernsen(m, 15, 15) })
+}
+
+func BenchmarkDilation(b *testing.B) { benchSerialParallel(b, dilation) }
+
+// The At/Set versions below are how these filters were written before they
+// worked on Pix slices, kept as a baseline for the benchmarks.
+
+func BenchmarkAtSetAverage(b *testing.B) {
+	img := benchImage()
+	for i := 0; i < b.N; i++ {
+		atSetAverage(img)
+	}
+}
+
+func BenchmarkAtSetNiblack(b *testing.B) {
+	img := benchImage()
+	for i := 0; i < b.N; i++ {
+		atSetNiblack(img, 15, -0.2)
+	}
+}
+
+func BenchmarkAtSetDilation(b *testing.B) {
+	img := benchImage()
+	for i := 0; i < b.N; i++ {
+		atSetDilation(img)
+	}
+}
+
+func atSetAverage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	newImg := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			var sumR, sumG, sumB, sumA, count int
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < bounds.Min.X || nx >= bounds.Max.X || ny < bounds.Min.Y || ny >= bounds.Max.Y {
+						continue
+					}
+					r16, g16, b16, a16 := img.At(nx, ny).RGBA()
+					sumR += int(r16 >> 8)
+					sumG += int(g16 >> 8)
+					sumB += int(b16 >> 8)
+					sumA += int(a16 >> 8)
+					count++
+				}
+			}
+			newImg.SetRGBA(x, y, color.RGBA{
+				uint8(sumR / count), uint8(sumG / count), uint8(sumB / count), uint8(sumA / count),
+			})
+		}
+	}
+	return newImg
+}
+
+func atSetNiblack(m image.Image, windowSize int, k float64) image.Image {
+	b := m.Bounds()
+	padding := windowSize / 2
+	binaryM := image.NewGray(b)
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			var sum, sumSq float64
+			var count int
+			for wy := -padding; wy <= padding; wy++ {
+				for wx := -padding; wx <= padding; wx++ {
+					nx, ny := x+wx, y+wy
+					if nx >= 0 && nx < b.Dx() && ny >= 0 && ny < b.Dy() {
+						lum := color.GrayModel.Convert(m.At(nx, ny)).(color.Gray).Y
+						sum += float64(lum)
+						sumSq += float64(lum) * float64(lum)
+						count++
+					}
+				}
+			}
+			mean := sum / float64(count)
+			threshold := mean + k*math.Sqrt(sumSq/float64(count)-mean*mean)
+			lum := color.GrayModel.Convert(m.At(x, y)).(color.Gray).Y
+			if float64(lum) > threshold {
+				binaryM.Set(x, y, color.Black)
+			} else {
+				binaryM.Set(x, y, color.White)
+			}
+		}
+	}
+	return binaryM
+}
+
+func atSetDilation(m image.Image) image.Image {
+	b := m.Bounds()
+	grayM := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			grayM.Set(x, y, color.GrayModel.Convert(m.At(x, y)))
+		}
+	}
+	dilatedM := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var maxVal uint8
+			for seY := 0; seY < 3; seY++ {
+				for seX := 0; seX < 3; seX++ {
+					if !crossElement[seY][seX] {
+						continue
+					}
+					nx, ny := x+seX-1, y+seY-1
+					if nx >= b.Min.X && nx < b.Max.X && ny >= b.Min.Y && ny < b.Max.Y {
+						maxVal = max(maxVal, grayM.GrayAt(nx, ny).Y)
+					}
+				}
+			}
+			dilatedM.SetGray(x, y, color.Gray{Y: maxVal})
+		}
+	}
+	return dilatedM
+}
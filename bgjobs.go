@@ -2,14 +2,15 @@ package main
 
 import (
 	"bytes"
-	"encoding/base64"
+	"context"
 	"fmt"
 	"image"
-	"image/jpeg"
 	"image/png"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
@@ -20,7 +21,8 @@ import (
 type Job struct {
 	ID       string
 	FilePath string
-	comments []string
+	metadata []MetadataEntry
+	exif     *Exif
 }
 
 type Worker struct {
@@ -28,6 +30,8 @@ type Worker struct {
 	jobStatus map[string]string
 	lock      sync.Mutex
 	app       *App
+	recent    *recentFiles
+	watcher   *fileWatcher
 }
 
 func NewWorker(app *App) *Worker {
@@ -35,11 +39,18 @@ func NewWorker(app *App) *Worker {
 		jobQueue:  make(chan Job, 1), // Buffer size set to 1
 		jobStatus: make(map[string]string),
 		app:       app,
+		recent:    newRecentFiles(),
+		watcher:   newFileWatcher(),
 	}
 	go worker.processJobs()
 	return worker
 }
 
+// startup starts reporting changed files once the runtime context exists.
+func (w *Worker) startup(ctx context.Context) {
+	go w.pollWatchedFiles(ctx)
+}
+
 func (w *Worker) UploadNetPbmImg() string {
 	filepath, err := runtime.OpenFileDialog(w.app.ctx, runtime.OpenDialogOptions{})
 	if err != nil {
@@ -49,7 +60,12 @@ func (w *Worker) UploadNetPbmImg() string {
 		return filepath
 	}
 	fmt.Println("Selected file:", filepath)
+	return w.ImportFile(filepath)
+}
 
+// ImportFile queues a file for import without a dialog, used for recent files
+// and for reloading files changed on disk.
+func (w *Worker) ImportFile(filepath string) string {
 	jobID := uuid.New().String()
 	job := Job{ID: jobID, FilePath: filepath}
 
@@ -69,47 +85,70 @@ func (w *Worker) processJobs() {
 
 		file, err := os.Open(job.FilePath)
 		if err != nil {
-			log.Fatal(err)
+			w.failJob(job, err)
+			continue
 		}
 
 		var (
 			img      image.Image
+			data     []byte
 			comments []string
+			metadata []MetadataEntry
+			exif     *Exif
 		)
-		switch filepath.Ext(job.FilePath) {
+		switch strings.ToLower(filepath.Ext(job.FilePath)) {
 		case ".jpg", ".jpeg":
-			img, err = jpeg.Decode(file)
+			if data, err = io.ReadAll(file); err != nil {
+				break
+			}
+			img, exif, err = decodeJpegWithExif(data)
+			if err == nil {
+				comments, _ = readJpegComments(data)
+				metadata = metadataFromComments(comments)
+			}
 		case ".png":
-			img, err = png.Decode(file)
+			if data, err = io.ReadAll(file); err != nil {
+				break
+			}
+			img, err = png.Decode(bytes.NewReader(data))
+			if err == nil {
+				metadata, _ = readPngMetadata(data)
+			}
 		case ".webp":
 			img, err = webp.Decode(file)
 		case ".pbm", ".pgm", ".ppm", ".pnm":
 			img, comments, err = parseNetPbm(file)
+			metadata = metadataFromComments(comments)
 		default:
-			w.updateStatus(job.ID, "failed")
+			err = fmt.Errorf("unsupported file extension '%s'", filepath.Ext(job.FilePath))
 		}
+		file.Close()
 
+		// A file being rewritten by another program may be caught half way,
+		// so failures are reported instead of stopping the worker
 		if err != nil {
-			w.updateStatus(job.ID, "failed")
-		}
-		if err != nil {
-			log.Fatal(err)
+			w.failJob(job, err)
+			continue
 		}
 
-		var buf bytes.Buffer
-		if err := png.Encode(&buf, img); err != nil {
-			panic(err)
-		}
-
-		base64str := base64.StdEncoding.EncodeToString(buf.Bytes())
+		w.recent.add(job.FilePath)
+		w.watcher.watch(job.FilePath)
+		ref := w.app.images.put(img)
 
-		job.comments = comments
+		job.metadata = metadata
+		job.exif = exif
 
 		w.updateStatus(job.ID, "completed")
-		runtime.EventsEmit(w.app.ctx, job.ID, job.comments, w.jobStatus[job.ID], base64str)
+		runtime.EventsEmit(w.app.ctx, job.ID, job.metadata, w.jobStatus[job.ID], ref, job.exif)
 	}
 }
 
+func (w *Worker) failJob(job Job, err error) {
+	log.Printf("import of %s failed: %v", job.FilePath, err)
+	w.updateStatus(job.ID, "failed")
+	runtime.EventsEmit(w.app.ctx, job.ID, job.metadata, w.GetJobStatus(job.ID), "", job.exif)
+}
+
 func (w *Worker) updateStatus(jobID, status string) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
@@ -1,12 +1,7 @@
 package main
 
 import (
-	"bytes"
-	"encoding/base64"
-	"fmt"
 	"image"
-	"image/color"
-	"image/png"
 	"math"
 	"slices"
 
@@ -20,36 +15,21 @@ func (a *App) HandleBinarizeManual(base64str string, threshold uint8) string {
 		})
 		return ""
 	}
-	m, err := decodeBasePngToImg(base64str, a.ctx)
+	m, err := a.imageFromRef(base64str)
 	if err != nil {
 		return ""
 	}
 	newM := binarizeManual(m, threshold)
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newM); err != nil {
-		return ""
-	}
-	base64str = base64.StdEncoding.EncodeToString(buf.Bytes())
-	dataUrl := fmt.Sprintf("data:image/png;base64,%s", base64str)
-	return dataUrl
+	return a.refFromImage(base64str, newM)
 }
 
 func binarizeManual(m image.Image, threshold uint8) image.Image {
-	bounds := m.Bounds()
-	width, height := bounds.Max.X, bounds.Max.Y
-	newM := image.NewGray(bounds)
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, _ := m.At(x, y).RGBA()
-			gray := uint8(0.299*float64(r/257) + 0.587*float64(g/257) + 0.114*float64(b/257))
-			if gray > threshold {
-				newM.Set(x, y, color.White)
-			} else {
-				newM.Set(x, y, color.Black)
-			}
-		}
-	}
-	return newM
+	pix, w, _ := premultiplied(m)
+	return binarize(m.Bounds(), nil, func(x, y int, _ uint8) bool {
+		p := pix[4*(y*w+x):]
+		gray := uint8(0.299*float64(p[0]/257) + 0.587*float64(p[1]/257) + 0.114*float64(p[2]/257))
+		return gray <= threshold
+	})
 }
 
 func (a *App) HandleBinarizePercentBlack(base64str string, percent float64) string {
@@ -59,52 +39,23 @@ func (a *App) HandleBinarizePercentBlack(base64str string, percent float64) stri
 		})
 		return ""
 	}
-	m, err := decodeBasePngToImg(base64str, a.ctx)
+	m, err := a.imageFromRef(base64str)
 	if err != nil {
 		return ""
 	}
 	newM := binalizePercentBlack(m, percent)
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newM); err != nil {
-		return ""
-	}
-	base64str = base64.StdEncoding.EncodeToString(buf.Bytes())
-	dataUrl := fmt.Sprintf("data:image/png;base64,%s", base64str)
-	return dataUrl
+	return a.refFromImage(base64str, newM)
 }
 
 func binalizePercentBlack(m image.Image, percent float64) image.Image {
-	b := m.Bounds()
-	pxCount := b.Dx() * b.Dy()
-	lums := make([]uint8, 0, pxCount)
-	for y := 0; y < b.Max.Y; y++ {
-		for x := 0; x < b.Max.X; x++ {
-			oldPx := m.At(x, y)
-			lum := color.GrayModel.Convert(oldPx).(color.Gray).Y
-			lums = append(lums, lum)
-		}
-	}
-
-	lumsSorted := make([]uint8, len(lums))
-	copy(lumsSorted, lums)
+	gray := toGray(m)
+	b := gray.Bounds()
+	lumsSorted := grayValues(gray)
 	slices.Sort(lumsSorted)
 
-	thresholdIdx := int((percent / 100) * float64(pxCount))
+	thresholdIdx := int((percent / 100) * float64(len(lumsSorted)))
 	threshold := lumsSorted[thresholdIdx]
-
-	binaryM := image.NewGray(b)
-	var loopIdx int
-	for y := 0; y < b.Dy(); y++ {
-		for x := 0; x < b.Dx(); x++ {
-			if lums[loopIdx] > threshold {
-				binaryM.Set(x, y, color.Black)
-			} else {
-				binaryM.Set(x, y, color.White)
-			}
-			loopIdx++
-		}
-	}
-	return binaryM
+	return binarize(b, gray, func(_, _ int, lum uint8) bool { return lum > threshold })
 }
 
 func (a *App) HandleBinarizeMeanIterative(base64str string, maxIterations int) string {
@@ -115,7 +66,7 @@ func (a *App) HandleBinarizeMeanIterative(base64str string, maxIterations int) s
 		return ""
 	}
 
-	m, err := decodeBasePngToImg(base64str, a.ctx)
+	m, err := a.imageFromRef(base64str)
 	if err != nil {
 		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
 			Message: "Failed to decode image",
@@ -124,27 +75,15 @@ func (a *App) HandleBinarizeMeanIterative(base64str string, maxIterations int) s
 	}
 
 	newM := binalizeMeanIterative(m, maxIterations)
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newM); err != nil {
-		return ""
-	}
-
-	base64str = base64.StdEncoding.EncodeToString(buf.Bytes())
-	return fmt.Sprintf("data:image/png;base64,%s", base64str)
+	return a.refFromImage(base64str, newM)
 }
 
 func binalizeMeanIterative(m image.Image, maxIterations int) image.Image {
-	b := m.Bounds()
-	pxCount := b.Dx() * b.Dy()
-	lums := make([]uint8, 0, pxCount)
+	gray := toGray(m)
+	lums := grayValues(gray)
 	var sum uint64
-	for y := 0; y < b.Dy(); y++ {
-		for x := 0; x < b.Dx(); x++ {
-			oldPx := m.At(x, y)
-			lum := color.GrayModel.Convert(oldPx).(color.Gray).Y
-			lums = append(lums, lum)
-			sum += uint64(lum)
-		}
+	for _, lum := range lums {
+		sum += uint64(lum)
 	}
 	threshold := uint8(sum / uint64(len(lums)))
 	for i := 0; i < maxIterations; i++ {
@@ -169,23 +108,11 @@ func binalizeMeanIterative(m image.Image, maxIterations int) image.Image {
 		}
 	}
 
-	binaryM := image.NewGray(b)
-	var loopIdx int
-	for y := 0; y < b.Dy(); y++ {
-		for x := 0; x < b.Dx(); x++ {
-			if lums[loopIdx] > threshold {
-				binaryM.Set(x, y, color.Black)
-			} else {
-				binaryM.Set(x, y, color.White)
-			}
-			loopIdx++
-		}
-	}
-	return binaryM
+	return binarize(gray.Bounds(), gray, func(_, _ int, lum uint8) bool { return lum > threshold })
 }
 
 func (a *App) HandleBinarizeOtsu(base64str string) string {
-	m, err := decodeBasePngToImg(base64str, a.ctx)
+	m, err := a.imageFromRef(base64str)
 	if err != nil {
 		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
 			Message: "Failed to decode image",
@@ -194,26 +121,18 @@ func (a *App) HandleBinarizeOtsu(base64str string) string {
 	}
 
 	newM := binarizeOtsu(m)
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newM); err != nil {
-		return ""
-	}
-
-	base64str = base64.StdEncoding.EncodeToString(buf.Bytes())
-	return fmt.Sprintf("data:image/png;base64,%s", base64str)
+	return a.refFromImage(base64str, newM)
 }
 
-func binarizeOtsu(m image.Image) image.Image {
-	b := m.Bounds()
+// otsuThreshold picks the gray level that maximises the between-class variance
+// of the luminance histogram.
+func otsuThreshold(m image.Image) uint8 {
+	lums := grayValues(toGray(m))
 	histogram := make([]int, 256)
-	for y := 0; y < b.Dy(); y++ {
-		for x := 0; x < b.Dx(); x++ {
-			oldPx := m.At(x, y)
-			lum := color.GrayModel.Convert(oldPx).(color.Gray).Y
-			histogram[lum]++
-		}
+	for _, lum := range lums {
+		histogram[lum]++
 	}
-	totalPixels := b.Dx() * b.Dy()
+	totalPixels := len(lums)
 	sumTotal := 0
 	for i := 0; i < 256; i++ {
 		sumTotal += i * histogram[i]
@@ -242,19 +161,12 @@ func binarizeOtsu(m image.Image) image.Image {
 			threshold = uint8(t)
 		}
 	}
-	binaryM := image.NewGray(b)
-	for y := 0; y < b.Dy(); y++ {
-		for x := 0; x < b.Dx(); x++ {
-			oldPx := m.At(x, y)
-			lum := color.GrayModel.Convert(oldPx).(color.Gray).Y
-			if lum > threshold {
-				binaryM.Set(x, y, color.Black)
-			} else {
-				binaryM.Set(x, y, color.White)
-			}
-		}
-	}
-	return binaryM
+	return threshold
+}
+
+func binarizeOtsu(m image.Image) image.Image {
+	threshold := otsuThreshold(m)
+	return binarize(m.Bounds(), toGray(m), func(_, _ int, lum uint8) bool { return lum > threshold })
 }
 
 func (a *App) HandleBinarizeNiblack(base64str string, windowSize int, k float64) string {
@@ -265,57 +177,40 @@ func (a *App) HandleBinarizeNiblack(base64str string, windowSize int, k float64)
 		return ""
 	}
 
-	m, err := decodeBasePngToImg(base64str, a.ctx)
+	m, err := a.imageFromRef(base64str)
 	if err != nil {
 		return ""
 	}
 
 	newM := binarizeNiblack(m, windowSize, k)
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newM); err != nil {
-		return ""
-	}
-
-	base64str = base64.StdEncoding.EncodeToString(buf.Bytes())
-	return fmt.Sprintf("data:image/png;base64,%s", base64str)
+	return a.refFromImage(base64str, newM)
 }
 
+// binarizeNiblack compares every pixel with mean + k * standard deviation of
+// its window. The window sums come from summed-area tables, so the cost does
+// not depend on the window size.
 func binarizeNiblack(m image.Image, windowSize int, k float64) image.Image {
-	b := m.Bounds()
+	gray := toGray(m)
+	b := gray.Bounds()
+	w, h := b.Dx(), b.Dy()
 	padding := windowSize / 2
-	binaryM := image.NewGray(b)
-
-	for y := 0; y < b.Dy(); y++ {
-		for x := 0; x < b.Dx(); x++ {
-			var sum, sumSq float64
-			var count int
-			for wy := -padding; wy <= padding; wy++ {
-				for wx := -padding; wx <= padding; wx++ {
-					nx, ny := x+wx, y+wy
-					if nx >= 0 && nx < b.Dx() && ny >= 0 && ny < b.Dy() {
-						px := m.At(nx, ny)
-						lum := color.GrayModel.Convert(px).(color.Gray).Y
-						sum += float64(lum)
-						sumSq += float64(lum) * float64(lum)
-						count++
-					}
-				}
-			}
-			mean := sum / float64(count)
-			variance := (sumSq / float64(count)) - (mean * mean)
-			stdDev := math.Sqrt(variance)
-			threshold := mean + k*stdDev
-			px := m.At(x, y)
-			lum := color.GrayModel.Convert(px).(color.Gray).Y
-			if float64(lum) > threshold {
-				binaryM.Set(x, y, color.Black)
-			} else {
-				binaryM.Set(x, y, color.White)
-			}
-		}
+	lums := grayValues(gray)
+	plane, squares := make([]float64, len(lums)), make([]float64, len(lums))
+	for i, lum := range lums {
+		plane[i], squares[i] = float64(lum), float64(lum)*float64(lum)
 	}
-
-	return binaryM
+	sums, sumsSq := newSummedArea(plane, w, h), newSummedArea(squares, w, h)
+
+	return binarize(b, gray, func(x, y int, lum uint8) bool {
+		x0, y0 := max(x-padding, 0), max(y-padding, 0)
+		x1, y1 := min(x+padding+1, w), min(y+padding+1, h)
+		count := float64((x1 - x0) * (y1 - y0))
+		mean := sums.sum(x0, y0, x1, y1) / count
+		variance := (sumsSq.sum(x0, y0, x1, y1) / count) - (mean * mean)
+		stdDev := math.Sqrt(variance)
+		threshold := mean + k*stdDev
+		return float64(lum) > threshold
+	})
 }
 
 func (a *App) HandleBinarizeBernsen(
@@ -323,19 +218,13 @@ func (a *App) HandleBinarizeBernsen(
 	windowSize int,
 	contrastThreshold uint8,
 ) string {
-	m, err := decodeBasePngToImg(base64str, a.ctx)
+	m, err := a.imageFromRef(base64str)
 	if err != nil {
 		return ""
 	}
 
 	newM := binarizeBernsen(m, windowSize, contrastThreshold)
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newM); err != nil {
-		return ""
-	}
-
-	base64str = base64.StdEncoding.EncodeToString(buf.Bytes())
-	return fmt.Sprintf("data:image/png;base64,%s", base64str)
+	return a.refFromImage(base64str, newM)
 }
 
 func binarizeBernsen(
@@ -344,58 +233,75 @@ func binarizeBernsen(
 	contrastThreshold uint8,
 ) image.Image {
 	padding := windowVal / 2
-	b := m.Bounds()
-	binM := image.NewGray(b)
-	minInt := func(a, b int) int {
-		if a > b {
-			return b
+	gray := toGray(m)
+	b := gray.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lums := grayValues(gray)
+
+	// the window minimum and maximum are separable: first along the rows,
+	// then along the columns of the row results
+	rowMin, rowMax := make([]uint8, len(lums)), make([]uint8, len(lums))
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				minLum, maxLum := uint8(255), uint8(0)
+				for _, lum := range lums[y*w+max(x-padding, 0) : y*w+min(x+padding, w-1)+1] {
+					minLum, maxLum = min(minLum, lum), max(maxLum, lum)
+				}
+				rowMin[y*w+x], rowMax[y*w+x] = minLum, maxLum
+			}
 		}
-		return a
-	}
-	maxInt := func(a, b int) int {
-		if a > b {
-			return a
+	})
+
+	return binarize(b, gray, func(x, y int, lum uint8) bool {
+		minLum, maxLum := uint8(255), uint8(0)
+		for winY := max(y-padding, 0); winY <= min(y+padding, h-1); winY++ {
+			minLum, maxLum = min(minLum, rowMin[winY*w+x]), max(maxLum, rowMax[winY*w+x])
 		}
-		return b
-	}
+		contrast := maxLum - minLum
+		if contrast >= contrastThreshold {
+			threshold := minLum/2 + maxLum/2
+			return lum < threshold
+		}
+		return false
+	})
+}
 
+// grayValues returns the pixels of a gray image row after row without gaps.
+func grayValues(gray *image.Gray) []uint8 {
+	b := gray.Bounds()
+	w := b.Dx()
+	values := make([]uint8, w*b.Dy())
 	for y := 0; y < b.Dy(); y++ {
-		for x := 0; x < b.Dx(); x++ {
-			var (
-				minLum uint8 = 255
-				maxLum uint8 = 0
-			)
-			yMin := maxInt(y-padding, 0)
-			yMax := minInt(y+padding, b.Dy()-1)
-			xMin := maxInt(x-padding, 0)
-			xMax := minInt(x+padding, b.Dx()-1)
-			for winY := yMin; winY <= yMax; winY++ {
-				for winX := xMin; winX <= xMax; winX++ {
-					px := m.At(winX, winY)
-					lum := color.GrayModel.Convert(px).(color.Gray).Y
-					if lum < minLum {
-						minLum = lum
-					}
-					if lum > maxLum {
-						maxLum = lum
-					}
-				}
+		copy(values[y*w:(y+1)*w], gray.Pix[gray.PixOffset(b.Min.X, b.Min.Y+y):])
+	}
+	return values
+}
+
+// binarize builds a black and white image in parallel. black is called with
+// coordinates relative to bounds and the luminance of the pixel in gray; gray
+// may be nil when the callback does not need it.
+func binarize(bounds image.Rectangle, gray *image.Gray, black func(x, y int, lum uint8) bool) *image.Gray {
+	result := image.NewGray(bounds)
+	parallelRows(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dst := result.Pix[y*result.Stride : y*result.Stride+bounds.Dx()]
+			var lums []uint8
+			if gray != nil {
+				lums = gray.Pix[gray.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
 			}
-			contrast := maxLum - minLum
-			if contrast >= contrastThreshold {
-				threshold := minLum/2 + maxLum/2
-				px := m.At(x, y)
-				lum := color.GrayModel.Convert(px).(color.Gray).Y
-				if lum < threshold {
-					binM.Set(x, y, color.Black)
+			for x := range dst {
+				var lum uint8
+				if lums != nil {
+					lum = lums[x]
+				}
+				if black(x, y, lum) {
+					dst[x] = 0
 				} else {
-					binM.Set(x, y, color.White)
+					dst[x] = 255
 				}
-			} else {
-				binM.Set(x, y, color.White)
 			}
 		}
-	}
-
-	return binM
+	})
+	return result
 }
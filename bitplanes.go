@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// bitPlaneCount is the number of bit planes of an 8-bit gray level.
+const bitPlaneCount = 8
+
+// bitPlanes slices the gray levels of the image into its bit planes, the
+// least significant first. A pixel is white in plane k when bit k of its
+// level is set.
+func bitPlanes(img image.Image) [bitPlaneCount]*image.Gray {
+	gray := toGray(img)
+	b := gray.Bounds()
+	var planes [bitPlaneCount]*image.Gray
+	for k := range planes {
+		planes[k] = image.NewGray(b)
+	}
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := gray.Pix[gray.PixOffset(b.Min.X, b.Min.Y+y):]
+			for k, plane := range planes {
+				dst := plane.Pix[y*plane.Stride:]
+				for x := 0; x < b.Dx(); x++ {
+					if row[x]>>k&1 == 1 {
+						dst[x] = 255
+					}
+				}
+			}
+		}
+	})
+	return planes
+}
+
+// keepBitPlanes keeps only the bits of the gray levels that are set in mask.
+func keepBitPlanes(img image.Image, mask uint8) *image.Gray {
+	gray := toGray(img)
+	b := gray.Bounds()
+	result := image.NewGray(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := gray.Pix[gray.PixOffset(b.Min.X, b.Min.Y+y):]
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < b.Dx(); x++ {
+				dst[x] = row[x] & mask
+			}
+		}
+	})
+	return result
+}
+
+// combineBitPlanes builds gray levels back from bit planes, the least
+// significant first. Bit k is set where plane k is at least mid-grey; nil
+// planes are left out. All planes must have the same size.
+func combineBitPlanes(planes [bitPlaneCount]image.Image) (*image.Gray, error) {
+	var size image.Point
+	var grays [bitPlaneCount]*image.Gray
+	for k, plane := range planes {
+		if plane == nil {
+			continue
+		}
+		grays[k] = toGray(plane)
+		if s := plane.Bounds().Size(); size == (image.Point{}) {
+			size = s
+		} else if s != size {
+			return nil, fmt.Errorf("bit plane %d is %v, the others are %v", k, s, size)
+		}
+	}
+	if size == (image.Point{}) {
+		return nil, errors.New("at least one bit plane is needed")
+	}
+	result := image.NewGray(image.Rectangle{Max: size})
+	parallelRows(size.Y, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dst := result.Pix[y*result.Stride:]
+			for k, gray := range grays {
+				if gray == nil {
+					continue
+				}
+				b := gray.Bounds()
+				row := gray.Pix[gray.PixOffset(b.Min.X, b.Min.Y+y):]
+				for x := 0; x < size.X; x++ {
+					if row[x] >= 128 {
+						dst[x] |= 1 << k
+					}
+				}
+			}
+		}
+	})
+	return result, nil
+}
+
+// HandleBitPlanes returns the 8 bit planes of the image, the least
+// significant first, in the same form as the input. The image itself is
+// left as it is; planes put in the registry stay there until the caller
+// releases them with ReleaseImage.
+func (a *App) HandleBitPlanes(base64str string) []string {
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return nil
+	}
+	planes := bitPlanes(m)
+	refs := make([]string, len(planes))
+	for k, plane := range planes {
+		refs[k] = a.encodeLike(base64str, plane)
+	}
+	return refs
+}
+
+// HandleCombineBitPlanes replaces the image with the gray levels built from
+// planes, as returned by HandleBitPlanes. Planes given as empty strings are
+// left out.
+func (a *App) HandleCombineBitPlanes(base64str string, planes []string) string {
+	if len(planes) != bitPlaneCount {
+		a.showInvalidParameters(fmt.Errorf("%d bit planes are needed, got %d", bitPlaneCount, len(planes)))
+		return ""
+	}
+	var images [bitPlaneCount]image.Image
+	for k, ref := range planes {
+		if ref == "" {
+			continue
+		}
+		m, err := a.imageFromRef(ref)
+		if err != nil {
+			runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+				Type:          runtime.InfoDialog,
+				Title:         "Decoding problem",
+				Message:       fmt.Sprintf("Bit plane %d could not be decoded: %s", k, err.Error()),
+				DefaultButton: "Ok",
+			})
+			return ""
+		}
+		images[k] = m
+	}
+	combined, err := combineBitPlanes(images)
+	if err != nil {
+		a.showInvalidParameters(err)
+		return ""
+	}
+	return a.refFromImage(base64str, combined)
+}
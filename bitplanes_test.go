@@ -0,0 +1,57 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestBitPlanesRoundTrip(t *testing.T) {
+	gray := image.NewGray(image.Rect(3, 2, 19, 18))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i)
+	}
+	planes := bitPlanes(gray)
+	if got := planes[0].GrayAt(4, 2).Y; got != 255 {
+		t.Errorf("level 1 should be white in plane 0, got %d", got)
+	}
+	if got := planes[7].GrayAt(4, 2).Y; got != 0 {
+		t.Errorf("level 1 should be black in plane 7, got %d", got)
+	}
+	var all [bitPlaneCount]image.Image
+	for k, plane := range planes {
+		all[k] = plane
+	}
+	combined, err := combineBitPlanes(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range gray.Pix {
+		if combined.Pix[i] != v {
+			t.Fatalf("level %d came back as %d", v, combined.Pix[i])
+		}
+	}
+	var high [bitPlaneCount]image.Image
+	high[7], high[6] = planes[7], planes[6]
+	combined, err = combineBitPlanes(high)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := keepBitPlanes(gray, 0xc0)
+	for i := range combined.Pix {
+		if combined.Pix[i] != kept.Pix[i] || kept.Pix[i] != gray.Pix[i]&0xc0 {
+			t.Fatalf("the two high planes of %d should give %d, got %d and %d", gray.Pix[i], gray.Pix[i]&0xc0, combined.Pix[i], kept.Pix[i])
+		}
+	}
+}
+
+func TestCombineBitPlanesRejects(t *testing.T) {
+	if _, err := combineBitPlanes([bitPlaneCount]image.Image{}); err == nil {
+		t.Error("combining no planes should fail")
+	}
+	var planes [bitPlaneCount]image.Image
+	planes[0] = image.NewGray(image.Rect(0, 0, 4, 4))
+	planes[3] = image.NewGray(image.Rect(0, 0, 4, 5))
+	if _, err := combineBitPlanes(planes); err == nil {
+		t.Error("planes of different sizes should be rejected")
+	}
+}
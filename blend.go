@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/image/draw"
+)
+
+type BlendMode string
+
+const (
+	blendAdd        BlendMode = "add"
+	blendSubtract   BlendMode = "subtract"
+	blendDifference BlendMode = "difference"
+	blendMultiply   BlendMode = "multiply"
+	blendDivide     BlendMode = "divide"
+	blendMin        BlendMode = "min"
+	blendMax        BlendMode = "max"
+	blendAverage    BlendMode = "average"
+	blendAnd        BlendMode = "and"
+	blendOr         BlendMode = "or"
+	blendXor        BlendMode = "xor"
+	blendNormal     BlendMode = "normal"
+	blendScreen     BlendMode = "screen"
+	blendOverlay    BlendMode = "overlay"
+	blendSoftLight  BlendMode = "softlight"
+	blendDarken     BlendMode = "darken"
+	blendLighten    BlendMode = "lighten"
+)
+
+var AllBlendModes = []struct {
+	Value  BlendMode
+	TSName string
+}{
+	{blendAdd, "add"},
+	{blendSubtract, "subtract"},
+	{blendDifference, "difference"},
+	{blendMultiply, "multiply"},
+	{blendDivide, "divide"},
+	{blendMin, "min"},
+	{blendMax, "max"},
+	{blendAverage, "average"},
+	{blendAnd, "and"},
+	{blendOr, "or"},
+	{blendXor, "xor"},
+	{blendNormal, "normal"},
+	{blendScreen, "screen"},
+	{blendOverlay, "overlay"},
+	{blendSoftLight, "softlight"},
+	{blendDarken, "darken"},
+	{blendLighten, "lighten"},
+}
+
+// bitwise applies op to channels in [0, 1] as 8-bit values, which is what
+// AND, OR and XOR of binary black and white images need.
+func bitwise(op func(a, b uint8) uint8) func(cb, cs float64) float64 {
+	return func(cb, cs float64) float64 {
+		return float64(op(clampUint8(cb*255), clampUint8(cs*255))) / 255
+	}
+}
+
+// blendFuncs combine a channel of the base image cb with the channel of the
+// image laid over it cs, both in [0, 1]. Blend modes follow the W3C
+// compositing specification; darken and lighten are min and max.
+var blendFuncs = map[BlendMode]func(cb, cs float64) float64{
+	blendAdd:        func(cb, cs float64) float64 { return min(1, cb+cs) },
+	blendSubtract:   func(cb, cs float64) float64 { return max(0, cb-cs) },
+	blendDifference: func(cb, cs float64) float64 { return math.Abs(cb - cs) },
+	blendMultiply:   func(cb, cs float64) float64 { return cb * cs },
+	blendDivide: func(cb, cs float64) float64 {
+		if cs == 0 {
+			if cb == 0 {
+				return 0
+			}
+			return 1
+		}
+		return min(1, cb/cs)
+	},
+	blendMin:     func(cb, cs float64) float64 { return min(cb, cs) },
+	blendMax:     func(cb, cs float64) float64 { return max(cb, cs) },
+	blendAverage: func(cb, cs float64) float64 { return (cb + cs) / 2 },
+	blendAnd:     bitwise(func(a, b uint8) uint8 { return a & b }),
+	blendOr:      bitwise(func(a, b uint8) uint8 { return a | b }),
+	blendXor:     bitwise(func(a, b uint8) uint8 { return a ^ b }),
+	blendNormal:  func(_, cs float64) float64 { return cs },
+	blendScreen:  func(cb, cs float64) float64 { return cb + cs - cb*cs },
+	blendOverlay: func(cb, cs float64) float64 {
+		if cb <= 0.5 {
+			return 2 * cb * cs
+		}
+		return 1 - 2*(1-cb)*(1-cs)
+	},
+	blendSoftLight: func(cb, cs float64) float64 {
+		if cs <= 0.5 {
+			return cb - (1-2*cs)*cb*(1-cb)
+		}
+		d := math.Sqrt(cb)
+		if cb <= 0.25 {
+			d = ((16*cb-12)*cb + 4) * cb
+		}
+		return cb + (2*cs-1)*(d-cb)
+	},
+	blendDarken:  func(cb, cs float64) float64 { return min(cb, cs) },
+	blendLighten: func(cb, cs float64) float64 { return max(cb, cs) },
+}
+
+type SizeFit string
+
+const (
+	fitResize  SizeFit = "resize"
+	fitTopLeft SizeFit = "topleft"
+	fitCenter  SizeFit = "center"
+)
+
+var AllSizeFits = []struct {
+	Value  SizeFit
+	TSName string
+}{
+	{fitResize, "resize"},
+	{fitTopLeft, "topleft"},
+	{fitCenter, "center"},
+}
+
+// BlendOptions configures HandleBlend. The second image is laid over the
+// base image and mixed in by Mode, then composited over it with its alpha
+// scaled by Opacity. The result always has the size of the base image: with
+// Fit resize the second image is stretched to it, otherwise it is aligned
+// to the top left corner or the centre and moved by OffsetX and OffsetY,
+// and the base image is left as it is where the second image does not
+// reach.
+type BlendOptions struct {
+	Mode    BlendMode `json:"mode"`
+	Opacity float64   `json:"opacity"`
+	Fit     SizeFit   `json:"fit"`
+	OffsetX int       `json:"offsetX"`
+	OffsetY int       `json:"offsetY"`
+}
+
+func (o BlendOptions) validate() error {
+	if _, ok := blendFuncs[o.Mode]; !ok {
+		return fmt.Errorf("unknown blend mode '%s'", o.Mode)
+	}
+	if math.IsNaN(o.Opacity) || o.Opacity < 0 || o.Opacity > 1 {
+		return fmt.Errorf("opacity must be between 0 and 1, got %g", o.Opacity)
+	}
+	if o.Fit != fitResize && o.Fit != fitTopLeft && o.Fit != fitCenter {
+		return fmt.Errorf("unknown size fit '%s'", o.Fit)
+	}
+	return nil
+}
+
+// fitTo returns top as a w x h image starting at the origin, transparent
+// where top does not cover it.
+func fitTo(top image.Image, w, h int, opts BlendOptions) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	tb := top.Bounds()
+	if opts.Fit == fitResize {
+		draw.BiLinear.Scale(dst, dst.Bounds(), top, tb, draw.Src, nil)
+		return dst
+	}
+	origin := image.Pt(opts.OffsetX, opts.OffsetY)
+	if opts.Fit == fitCenter {
+		origin = origin.Add(image.Pt((w-tb.Dx())/2, (h-tb.Dy())/2))
+	}
+	draw.Draw(dst, image.Rectangle{origin, origin.Add(tb.Size())}, top, tb.Min, draw.Src)
+	return dst
+}
+
+func blend(base, top image.Image, opts BlendOptions) image.Image {
+	src := toNRGBA(base)
+	b := base.Bounds()
+	over := fitTo(top, b.Dx(), b.Dy(), opts)
+	mix := blendFuncs[opts.Mode]
+	result := image.NewNRGBA(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			overRow := over.Pix[y*over.Stride:]
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < b.Dx(); x++ {
+				p, q, out := row[4*x:4*x+4], overRow[4*x:4*x+4], dst[4*x:4*x+4]
+				ab, as := float64(p[3])/255, float64(q[3])/255*opts.Opacity
+				ao := as + ab*(1-as)
+				if as == 0 || ao == 0 {
+					copy(out, p)
+					continue
+				}
+				for c := 0; c < 3; c++ {
+					cb, cs := float64(p[c])/255, float64(q[c])/255
+					// where the base is transparent the second image shows
+					// as it is, where it is opaque the blend result shows
+					blended := (1-ab)*cs + ab*mix(cb, cs)
+					out[c] = clampUint8(255 * (as*blended + (1-as)*ab*cb) / ao)
+				}
+				out[3] = clampUint8(255 * ao)
+			}
+		}
+	})
+	return result
+}
+
+// HandleBlend combines the image with a second image reference or data URL,
+// see BlendOptions.
+func (a *App) HandleBlend(base64str string, other string, opts BlendOptions) string {
+	if err := opts.validate(); err != nil {
+		a.showInvalidParameters(err)
+		return ""
+	}
+	top, err := a.imageFromRef(other)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Second image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return blend(m, top, opts)
+	})
+}
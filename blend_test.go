@@ -0,0 +1,142 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func onePixel(c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, c)
+	return img
+}
+
+func TestBlendModes(t *testing.T) {
+	base := onePixel(color.NRGBA{200, 100, 0, 255})
+	top := onePixel(color.NRGBA{100, 50, 255, 255})
+	tests := map[BlendMode]color.NRGBA{
+		blendAdd:        {255, 150, 255, 255},
+		blendSubtract:   {100, 50, 0, 255},
+		blendDifference: {100, 50, 255, 255},
+		blendMultiply:   {78, 20, 0, 255},
+		blendDivide:     {255, 255, 0, 255},
+		blendMin:        {100, 50, 0, 255},
+		blendMax:        {200, 100, 255, 255},
+		blendAverage:    {150, 75, 128, 255},
+		blendAnd:        {64, 32, 0, 255},
+		blendOr:         {236, 118, 255, 255},
+		blendXor:        {172, 86, 255, 255},
+		blendNormal:     {100, 50, 255, 255},
+		blendScreen:     {222, 130, 255, 255},
+		blendOverlay:    {188, 39, 0, 255},
+		blendDarken:     {100, 50, 0, 255},
+		blendLighten:    {200, 100, 255, 255},
+	}
+	for mode, want := range tests {
+		opts := BlendOptions{Mode: mode, Opacity: 1, Fit: fitTopLeft}
+		if err := opts.validate(); err != nil {
+			t.Fatal(err)
+		}
+		if got := blend(base, top, opts).(*image.NRGBA).NRGBAAt(0, 0); got != want {
+			t.Errorf("%s: got %v, want %v", mode, got, want)
+		}
+	}
+}
+
+func TestSoftLightKeepsEnds(t *testing.T) {
+	soft := blendFuncs[blendSoftLight]
+	for _, cb := range []float64{0, 0.2, 0.5, 0.9, 1} {
+		if got := soft(cb, 0.5); got != cb {
+			t.Errorf("soft light with mid-grey should keep %g, got %g", cb, got)
+		}
+	}
+	if soft(0.5, 1) <= 0.5 || soft(0.5, 0) >= 0.5 {
+		t.Error("soft light should lighten with white and darken with black")
+	}
+}
+
+func TestBlendOpacityAndAlpha(t *testing.T) {
+	black, white := onePixel(color.NRGBA{0, 0, 0, 255}), onePixel(color.NRGBA{255, 255, 255, 255})
+	tests := []struct {
+		name      string
+		base, top *image.NRGBA
+		opts      BlendOptions
+		want      color.NRGBA
+	}{
+		{"half opacity", black, white, BlendOptions{Mode: blendNormal, Opacity: 0.5}, color.NRGBA{128, 128, 128, 255}},
+		{"zero opacity", black, white, BlendOptions{Mode: blendScreen, Opacity: 0}, color.NRGBA{0, 0, 0, 255}},
+		{"transparent base", onePixel(color.NRGBA{0, 0, 0, 0}), onePixel(color.NRGBA{255, 0, 0, 255}),
+			BlendOptions{Mode: blendMultiply, Opacity: 1}, color.NRGBA{255, 0, 0, 255}},
+		{"transparent top", white, onePixel(color.NRGBA{0, 0, 0, 0}), BlendOptions{Mode: blendMultiply, Opacity: 1}, color.NRGBA{255, 255, 255, 255}},
+		{"half transparent over transparent", onePixel(color.NRGBA{0, 0, 0, 0}), onePixel(color.NRGBA{0, 0, 255, 128}),
+			BlendOptions{Mode: blendNormal, Opacity: 1}, color.NRGBA{0, 0, 255, 128}},
+	}
+	for _, tt := range tests {
+		tt.opts.Fit = fitResize
+		if got := blend(tt.base, tt.top, tt.opts).(*image.NRGBA).NRGBAAt(0, 0); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBlendSizeMismatch(t *testing.T) {
+	base := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := 3; i < len(base.Pix); i += 4 {
+		base.Pix[i] = 255
+	}
+	top := image.NewNRGBA(image.Rect(5, 5, 7, 7))
+	for i := range top.Pix {
+		top.Pix[i] = 255
+	}
+	tests := []struct {
+		opts  BlendOptions
+		white []image.Point
+	}{
+		{BlendOptions{Fit: fitTopLeft}, []image.Point{{0, 0}, {1, 1}}},
+		{BlendOptions{Fit: fitTopLeft, OffsetX: 2, OffsetY: 1}, []image.Point{{2, 1}, {3, 2}}},
+		{BlendOptions{Fit: fitCenter}, []image.Point{{1, 1}, {2, 2}}},
+		{BlendOptions{Fit: fitCenter, OffsetX: 5}, nil},
+		{BlendOptions{Fit: fitResize}, []image.Point{{0, 0}, {1, 2}, {3, 3}}},
+	}
+	for _, tt := range tests {
+		tt.opts.Mode, tt.opts.Opacity = blendNormal, 1
+		got := blend(base, top, tt.opts).(*image.NRGBA)
+		whites := 0
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				if got.NRGBAAt(x, y).R == 255 {
+					whites++
+				}
+			}
+		}
+		for _, p := range tt.white {
+			if got.NRGBAAt(p.X, p.Y).R != 255 {
+				t.Errorf("%+v: %v should be covered", tt.opts, p)
+			}
+		}
+		want := 4
+		switch {
+		case tt.opts.Fit == fitResize:
+			want = 16
+		case tt.white == nil:
+			want = 0
+		}
+		if whites != want {
+			t.Errorf("%+v: %d pixels covered, want %d", tt.opts, whites, want)
+		}
+	}
+}
+
+func TestBlendOptionsRejects(t *testing.T) {
+	invalid := []BlendOptions{
+		{Mode: "hue", Opacity: 1, Fit: fitResize},
+		{Mode: blendAdd, Opacity: 1.5, Fit: fitResize},
+		{Mode: blendAdd, Opacity: 1, Fit: "tile"},
+	}
+	for _, opts := range invalid {
+		if err := opts.validate(); err == nil {
+			t.Errorf("%+v should be rejected", opts)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// CannyThresholds chooses where the hysteresis thresholds come from.
+type CannyThresholds string
+
+const (
+	cannyManual CannyThresholds = "manual"
+	cannyMedian CannyThresholds = "median"
+	cannyOtsu   CannyThresholds = "otsu"
+)
+
+var AllCannyThresholds = []struct {
+	Value  CannyThresholds
+	TSName string
+}{
+	{cannyManual, "manual"},
+	{cannyMedian, "median"},
+	{cannyOtsu, "otsu"},
+}
+
+// CannyOptions configures HandleCanny. Low and High are compared with the
+// Sobel gradient magnitude of the 0-255 luminance and are only used with
+// manual thresholds. A zero Sigma skips the smoothing.
+type CannyOptions struct {
+	Sigma      float64         `json:"sigma"`
+	Low        float64         `json:"low"`
+	High       float64         `json:"high"`
+	Thresholds CannyThresholds `json:"thresholds"`
+}
+
+func (o CannyOptions) validate() error {
+	if o.Sigma < 0 {
+		return fmt.Errorf("sigma must not be negative, got %g", o.Sigma)
+	}
+	switch o.Thresholds {
+	case cannyManual:
+		if o.Low < 0 || o.High < o.Low {
+			return fmt.Errorf("thresholds must satisfy 0 <= low <= high, got %g and %g", o.Low, o.High)
+		}
+	case cannyMedian, cannyOtsu:
+	default:
+		return fmt.Errorf("unknown threshold mode '%s'", o.Thresholds)
+	}
+	return nil
+}
+
+// thresholds returns the hysteresis thresholds. The automatic ones scale a
+// representative gray level of the image: 0.66 and 1.33 times the median, or
+// half of and the full Otsu value.
+func (o CannyOptions) thresholds(img image.Image) (low, high float64) {
+	switch o.Thresholds {
+	case cannyMedian:
+		median := float64(medianLuminance(img))
+		return 0.66 * median, 1.33 * median
+	case cannyOtsu:
+		otsu := float64(otsuThreshold(img))
+		return otsu / 2, otsu
+	default:
+		return o.Low, o.High
+	}
+}
+
+func medianLuminance(img image.Image) uint8 {
+	gray := toGray(img)
+	b := gray.Bounds()
+	var histogram [256]int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for _, v := range gray.Pix[gray.PixOffset(b.Min.X, y):gray.PixOffset(b.Max.X, y)] {
+			histogram[v]++
+		}
+	}
+	half := b.Dx() * b.Dy() / 2
+	count := 0
+	for v, n := range histogram {
+		count += n
+		if count > half {
+			return uint8(v)
+		}
+	}
+	return 255
+}
+
+// nonMaximumSuppression keeps a pixel only if its magnitude is a maximum
+// along the gradient direction, rounded to 45 degrees. Ties are broken
+// towards the backward neighbour, so a plateau of two equal pixels still
+// gives a one pixel wide edge.
+func nonMaximumSuppression(gx, gy, magnitude []float64, w, h int) []float64 {
+	out := make([]float64, len(magnitude))
+	at := func(x, y int) float64 {
+		if x < 0 || x >= w || y < 0 || y >= h {
+			return 0
+		}
+		return magnitude[y*w+x]
+	}
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				i := y*w + x
+				if magnitude[i] == 0 {
+					continue
+				}
+				angle := math.Atan2(gy[i], gx[i]) * 180 / math.Pi
+				if angle < 0 {
+					angle += 180
+				}
+				var dx, dy int
+				switch {
+				case angle < 22.5 || angle >= 157.5:
+					dx, dy = 1, 0
+				case angle < 67.5:
+					dx, dy = 1, 1
+				case angle < 112.5:
+					dx, dy = 0, 1
+				default:
+					dx, dy = -1, 1
+				}
+				if magnitude[i] > at(x+dx, y+dy) && magnitude[i] >= at(x-dx, y-dy) {
+					out[i] = magnitude[i]
+				}
+			}
+		}
+	})
+	return out
+}
+
+// hysteresis marks pixels above high as edges and grows them through
+// 8-connected pixels above low.
+func hysteresis(magnitude []float64, w, h int, low, high float64) []bool {
+	edges := make([]bool, len(magnitude))
+	var stack []int
+	for i, v := range magnitude {
+		if v > 0 && v >= high {
+			edges[i] = true
+			stack = append(stack, i)
+		}
+	}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x, y := i%w, i/w
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || nx >= w || ny < 0 || ny >= h {
+					continue
+				}
+				j := ny*w + nx
+				if !edges[j] && magnitude[j] > 0 && magnitude[j] >= low {
+					edges[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+	}
+	return edges
+}
+
+// canny returns white one pixel wide edges on black, with the alpha of the
+// source.
+func canny(img image.Image, opts CannyOptions) image.Image {
+	plane, w, h := luminancePlane(img)
+	if opts.Sigma > 0 {
+		plane = blurPlane(plane, w, h, gaussianKernel1D(opts.Sigma, 0))
+	}
+	gx, gy := imageGradient(plane, w, h, edgeSobel)
+	magnitude := make([]float64, len(plane))
+	for i := range magnitude {
+		magnitude[i] = math.Hypot(gx[i], gy[i])
+	}
+	low, high := opts.thresholds(img)
+	edges := hysteresis(nonMaximumSuppression(gx, gy, magnitude, w, h), w, h, low, high)
+
+	b := img.Bounds()
+	src := toNRGBA(img)
+	result := image.NewNRGBA(b)
+	for i, edge := range edges {
+		x, y := i%w, i/w
+		out := result.Pix[y*result.Stride+4*x:]
+		if edge {
+			out[0], out[1], out[2] = 255, 255, 255
+		}
+		out[3] = src.Pix[src.PixOffset(b.Min.X+x, b.Min.Y+y)+3]
+	}
+	return result
+}
+
+func (a *App) HandleCanny(base64str string, opts CannyOptions) string {
+	if err := opts.validate(); err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Invalid parameters",
+			Message:       err.Error(),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	return a.refFromImage(base64str, canny(m, opts))
+}
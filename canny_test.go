@@ -0,0 +1,56 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCannySquare(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 20, 20))
+	for y := 6; y < 14; y++ {
+		for x := 6; x < 14; x++ {
+			img.SetGray(x, y, color.Gray{200})
+		}
+	}
+	for _, opts := range []CannyOptions{
+		{Sigma: 0, Low: 50, High: 150, Thresholds: cannyManual},
+		{Sigma: 0.5, Low: 50, High: 150, Thresholds: cannyManual},
+		{Sigma: 1, Low: 50, High: 150, Thresholds: cannyManual},
+		{Sigma: 1, Thresholds: cannyOtsu},
+	} {
+		out := canny(img, opts).(*image.NRGBA)
+		for _, p := range []image.Point{{2, 2}, {10, 10}, {17, 10}} {
+			if out.NRGBAAt(p.X, p.Y).R != 0 {
+				t.Errorf("%s: unexpected edge at %v", opts.Thresholds, p)
+			}
+		}
+		// every row crossing the square has exactly one edge pixel on each side
+		for y := 8; y < 12; y++ {
+			left, right := 0, 0
+			for x := 0; x < 20; x++ {
+				if out.NRGBAAt(x, y).R != 255 {
+					continue
+				}
+				if x < 10 {
+					left++
+				} else {
+					right++
+				}
+			}
+			if left != 1 || right != 1 {
+				t.Errorf("%s, sigma %g: row %d has %d and %d edge pixels on the left and right side, want 1 and 1",
+					opts.Thresholds, opts.Sigma, y, left, right)
+			}
+		}
+	}
+}
+
+func TestCannyOptionsValidate(t *testing.T) {
+	if err := (CannyOptions{Low: 100, High: 50, Thresholds: cannyManual}).validate(); err == nil {
+		t.Error("low above high was accepted")
+	}
+	if err := (CannyOptions{Sigma: 1.4, Thresholds: cannyMedian}).validate(); err != nil {
+		t.Error(err)
+	}
+}
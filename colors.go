@@ -1,6 +1,9 @@
 package main
 
-import "image/color"
+import (
+	"image/color"
+	"math"
+)
 
 type Cmyk struct {
 	C uint8 `json:"c"`
@@ -29,3 +32,54 @@ func (a *App) CmykToRgb(c, m, y, k uint8) Rgb {
 	r, g, b := color.CMYKToRGB(c, m, y, k)
 	return Rgb{r, g, b}
 }
+
+// hueOf returns the hue in degrees of a colour whose largest channel is hi
+// and whose chroma is chroma, which must not be 0.
+func hueOf(r, g, b, hi, chroma float64) float64 {
+	var h float64
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/chroma, 6)
+	case g:
+		h = (b-r)/chroma + 2
+	default:
+		h = (r-g)/chroma + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// rgbToHsv converts channels in [0, 1] to hue in degrees and saturation and
+// value in [0, 1]. Grays have hue 0.
+func rgbToHsv(r, g, b float64) (h, s, v float64) {
+	hi, lo := max(r, g, b), min(r, g, b)
+	if hi == lo {
+		return 0, 0, hi
+	}
+	return hueOf(r, g, b, hi, hi-lo), (hi - lo) / hi, hi
+}
+
+// rgbToHsl converts channels in [0, 1] to hue in degrees and saturation and
+// lightness in [0, 1]. Grays have hue 0.
+func rgbToHsl(r, g, b float64) (h, s, l float64) {
+	hi, lo := max(r, g, b), min(r, g, b)
+	l = (hi + lo) / 2
+	if hi == lo {
+		return 0, 0, l
+	}
+	return hueOf(r, g, b, hi, hi-lo), (hi - lo) / (1 - math.Abs(2*l-1)), l
+}
+
+// hslToRgb converts hue in degrees and saturation and lightness in [0, 1].
+func hslToRgb(h, s, l float64) (r, g, b float64) {
+	c := (1 - math.Abs(2*l-1)) * s
+	if c == 0 {
+		return l, l, l
+	}
+	// the same hue with value l + c/2 and chroma c
+	v := l + c/2
+	return hsvToRgb(h, c/v, v)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// BorderMode decides which pixel is read when the kernel reaches past the
+// image edge.
+type BorderMode string
+
+const (
+	borderClamp    BorderMode = "clamp"
+	borderWrap     BorderMode = "wrap"
+	borderReflect  BorderMode = "reflect"
+	borderConstant BorderMode = "constant"
+)
+
+var AllBorderModes = []struct {
+	Value  BorderMode
+	TSName string
+}{
+	{borderClamp, "clamp"},
+	{borderWrap, "wrap"},
+	{borderReflect, "reflect"},
+	{borderConstant, "constant"},
+}
+
+// maxKernelDivisor bounds the divisor an operation accepts.
+const maxKernelDivisor = 1 << 16
+
+// ConvolutionOptions describes a user kernel. Kernel rows must all have the
+// same odd length and there must be an odd number of them. A zero Divisor
+// means the sum of the kernel, or 1 if the kernel sums to 0. An empty Border
+// clamps.
+type ConvolutionOptions struct {
+	Kernel   [][]float64 `json:"kernel"`
+	Divisor  float64     `json:"divisor"`
+	Bias     float64     `json:"bias"`
+	Border   BorderMode  `json:"border"`
+	Constant Rgb         `json:"constant"`
+}
+
+func (o ConvolutionOptions) validate() error {
+	if len(o.Kernel) == 0 || len(o.Kernel)%2 == 0 {
+		return fmt.Errorf("kernel must have an odd number of rows, got %d", len(o.Kernel))
+	}
+	width := len(o.Kernel[0])
+	if width%2 == 0 {
+		return fmt.Errorf("kernel must have an odd number of columns, got %d", width)
+	}
+	for i, row := range o.Kernel {
+		if len(row) != width {
+			return fmt.Errorf("kernel row %d has %d values, expected %d", i+1, len(row), width)
+		}
+	}
+	switch o.Border {
+	case "", borderClamp, borderWrap, borderReflect, borderConstant:
+	default:
+		return fmt.Errorf("unknown border mode '%s'", o.Border)
+	}
+	return nil
+}
+
+// parseKernel reads a kernel written one row per line or with rows separated
+// by ';', values separated by spaces or commas.
+func parseKernel(s string) ([][]float64, error) {
+	var kernel [][]float64
+	for _, line := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == ';' }) {
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+		if len(fields) == 0 {
+			continue
+		}
+		row := make([]float64, len(fields))
+		for i, field := range fields {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("kernel value '%s' is not a number", field)
+			}
+			row[i] = v
+		}
+		kernel = append(kernel, row)
+	}
+	return kernel, nil
+}
+
+func (o ConvolutionOptions) divisor() float64 {
+	if o.Divisor != 0 {
+		return o.Divisor
+	}
+	var sum float64
+	for _, row := range o.Kernel {
+		for _, v := range row {
+			sum += v
+		}
+	}
+	if sum == 0 {
+		return 1
+	}
+	return sum
+}
+
+// borderIndex maps i into [0, n) according to mode. ok is false when the
+// constant colour has to be used instead.
+func borderIndex(i, n int, mode BorderMode) (idx int, ok bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+	switch mode {
+	case borderWrap:
+		return ((i % n) + n) % n, true
+	case borderReflect:
+		// edge pixel repeated: cba|abc|cba, period 2n for kernels wider than the image
+		period := 2 * n
+		i = ((i % period) + period) % period
+		if i >= n {
+			i = period - 1 - i
+		}
+		return i, true
+	case borderConstant:
+		return 0, false
+	default:
+		return min(max(i, 0), n-1), true
+	}
+}
+
+func clampUint8(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+// convolve applies the kernel to the colour channels. Alpha of the source
+// pixel is kept. Kernels may have negative weights, so alpha cannot be
+// convolved with the colour; instead a neighbour that is (partly) transparent
+// is read as a mix of its colour and the centre colour by its opacity, which
+// keeps the transparent black around a shape from bleeding into it.
+func convolve(img image.Image, opts ConvolutionOptions) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	pix := toNRGBA(img)
+	src := make([]color.NRGBA, w*h)
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := pix.Pix[pix.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				src[y*w+x] = color.NRGBA{row[4*x], row[4*x+1], row[4*x+2], row[4*x+3]}
+			}
+		}
+	})
+
+	constant := color.NRGBA{opts.Constant.R, opts.Constant.G, opts.Constant.B, 255}
+	halfH, halfW := len(opts.Kernel)/2, len(opts.Kernel[0])/2
+	divisor := opts.divisor()
+	result := image.NewNRGBA(b)
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				centre := src[y*w+x]
+				var sumR, sumG, sumB float64
+				for ky, row := range opts.Kernel {
+					sy, yOk := borderIndex(y+ky-halfH, h, opts.Border)
+					for kx, weight := range row {
+						if weight == 0 {
+							continue
+						}
+						sx, xOk := borderIndex(x+kx-halfW, w, opts.Border)
+						px := constant
+						if yOk && xOk {
+							px = src[sy*w+sx]
+						}
+						opacity := float64(px.A) / 255
+						sumR += (opacity*float64(px.R) + (1-opacity)*float64(centre.R)) * weight
+						sumG += (opacity*float64(px.G) + (1-opacity)*float64(centre.G)) * weight
+						sumB += (opacity*float64(px.B) + (1-opacity)*float64(centre.B)) * weight
+					}
+				}
+				dst := result.Pix[y*result.Stride+4*x:]
+				dst[0] = clampUint8(sumR/divisor + opts.Bias)
+				dst[1] = clampUint8(sumG/divisor + opts.Bias)
+				dst[2] = clampUint8(sumB/divisor + opts.Bias)
+				dst[3] = centre.A
+			}
+		}
+	})
+	return result
+}
+
+func (a *App) HandleConvolution(base64str string, opts ConvolutionOptions) string {
+	if err := opts.validate(); err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Invalid kernel",
+			Message:       err.Error(),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	return a.refFromImage(base64str, convolve(m, opts))
+}
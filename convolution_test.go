@@ -0,0 +1,82 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestBorderIndex(t *testing.T) {
+	tests := []struct {
+		i      int
+		mode   BorderMode
+		want   int
+		wantOk bool
+	}{
+		{-1, borderClamp, 0, true},
+		{5, borderClamp, 3, true},
+		{-1, borderWrap, 3, true},
+		{5, borderWrap, 1, true},
+		{-1, borderReflect, 0, true},
+		{-2, borderReflect, 1, true},
+		{4, borderReflect, 3, true},
+		{9, borderReflect, 1, true},
+		{-1, borderConstant, 0, false},
+		{2, borderConstant, 2, true},
+	}
+	for _, tt := range tests {
+		got, ok := borderIndex(tt.i, 4, tt.mode)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("borderIndex(%d, 4, %s) = %d, %v, want %d, %v",
+				tt.i, tt.mode, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestConvolve(t *testing.T) {
+	testImg := createTestImage()
+
+	identity := ConvolutionOptions{
+		Kernel: [][]float64{{0, 0, 0}, {0, 1, 0}, {0, 0, 0}},
+		Border: borderWrap,
+	}
+	if err := identity.validate(); err != nil {
+		t.Fatal(err)
+	}
+	out := convolve(testImg, identity)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			if out.At(x, y) != color.NRGBAModel.Convert(testImg.At(x, y)) {
+				t.Errorf("identity kernel changed pixel (%d,%d)", x, y)
+			}
+		}
+	}
+
+	// 1x3 horizontal box with a white constant border
+	box := ConvolutionOptions{
+		Kernel:   [][]float64{{1, 1, 1}},
+		Border:   borderConstant,
+		Constant: Rgb{255, 255, 255},
+	}
+	if err := box.validate(); err != nil {
+		t.Fatal(err)
+	}
+	out = convolve(testImg, box)
+	r, _, _, _ := out.At(0, 0).RGBA()
+	if r>>8 != 85 {
+		t.Errorf("constant border at (0,0): got %d, want 85", r>>8)
+	}
+	r, _, _, _ = out.At(0, 1).RGBA()
+	if r>>8 != 170 {
+		t.Errorf("constant border at (0,1): got %d, want 170", r>>8)
+	}
+
+	even := ConvolutionOptions{Kernel: [][]float64{{1, 1, 1}, {1, 1, 1}}}
+	if err := even.validate(); err == nil {
+		t.Error("even row count should be rejected")
+	}
+
+	if _, ok := out.(*image.NRGBA); !ok {
+		t.Errorf("expected *image.NRGBA result, got %T", out)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+type EdgeOperator string
+
+const (
+	edgeSobel      EdgeOperator = "sobel"
+	edgePrewitt    EdgeOperator = "prewitt"
+	edgeRoberts    EdgeOperator = "roberts"
+	edgeScharr     EdgeOperator = "scharr"
+	edgeLaplacian4 EdgeOperator = "laplacian4"
+	edgeLaplacian8 EdgeOperator = "laplacian8"
+	edgeLoG        EdgeOperator = "log"
+)
+
+var AllEdgeOperators = []struct {
+	Value  EdgeOperator
+	TSName string
+}{
+	{edgeSobel, "sobel"},
+	{edgePrewitt, "prewitt"},
+	{edgeRoberts, "roberts"},
+	{edgeScharr, "scharr"},
+	{edgeLaplacian4, "laplacian4"},
+	{edgeLaplacian8, "laplacian8"},
+	{edgeLoG, "log"},
+}
+
+// EdgeOutput selects what is drawn from the gradient. The Laplacian operators
+// have no direction, so only magnitude is available for them.
+type EdgeOutput string
+
+const (
+	edgeMagnitude EdgeOutput = "magnitude"
+	edgeX         EdgeOutput = "x"
+	edgeY         EdgeOutput = "y"
+	edgeDirection EdgeOutput = "direction"
+)
+
+var AllEdgeOutputs = []struct {
+	Value  EdgeOutput
+	TSName string
+}{
+	{edgeMagnitude, "magnitude"},
+	{edgeX, "x"},
+	{edgeY, "y"},
+	{edgeDirection, "direction"},
+}
+
+// EdgeOptions configures HandleEdgeDetection. Sigma is only used by the
+// Laplacian of Gaussian. With Normalize set the output is stretched so the
+// strongest edge is white, otherwise responses are drawn as they are and
+// clipped, so faint noise stays dark.
+type EdgeOptions struct {
+	Operator  EdgeOperator `json:"operator"`
+	Output    EdgeOutput   `json:"output"`
+	Sigma     float64      `json:"sigma"`
+	Normalize bool         `json:"normalize"`
+}
+
+// gradientKernels holds the X and Y kernel of each first-order operator.
+// Roberts cross is padded to 3x3 with the 2x2 cross in the bottom-right
+// corner.
+var gradientKernels = map[EdgeOperator][2][3][3]float64{
+	edgeSobel: {
+		{{-1, 0, 1}, {-2, 0, 2}, {-1, 0, 1}},
+		{{-1, -2, -1}, {0, 0, 0}, {1, 2, 1}},
+	},
+	edgePrewitt: {
+		{{-1, 0, 1}, {-1, 0, 1}, {-1, 0, 1}},
+		{{-1, -1, -1}, {0, 0, 0}, {1, 1, 1}},
+	},
+	edgeRoberts: {
+		{{0, 0, 0}, {0, 1, 0}, {0, 0, -1}},
+		{{0, 0, 0}, {0, 0, 1}, {0, -1, 0}},
+	},
+	edgeScharr: {
+		{{-3, 0, 3}, {-10, 0, 10}, {-3, 0, 3}},
+		{{-3, -10, -3}, {0, 0, 0}, {3, 10, 3}},
+	},
+}
+
+var laplacianKernels = map[EdgeOperator][3][3]float64{
+	edgeLaplacian4: {{0, 1, 0}, {1, -4, 1}, {0, 1, 0}},
+	edgeLaplacian8: {{1, 1, 1}, {1, -8, 1}, {1, 1, 1}},
+}
+
+func (o EdgeOptions) validate() error {
+	_, gradient := gradientKernels[o.Operator]
+	switch {
+	case gradient:
+	case o.Operator == edgeLaplacian4 || o.Operator == edgeLaplacian8:
+	case o.Operator == edgeLoG:
+		if o.Sigma <= 0 {
+			return fmt.Errorf("sigma must be positive, got %g", o.Sigma)
+		}
+	default:
+		return fmt.Errorf("unknown edge operator '%s'", o.Operator)
+	}
+	switch o.Output {
+	case edgeMagnitude:
+	case edgeX, edgeY, edgeDirection:
+		if !gradient {
+			return fmt.Errorf("%s has no direction, only magnitude output is available", o.Operator)
+		}
+	default:
+		return fmt.Errorf("unknown output '%s'", o.Output)
+	}
+	return nil
+}
+
+// luminancePlane returns the Rec. 601 luma of every pixel, the same weights
+// as the grayscale conversion.
+func luminancePlane(img image.Image) (plane []float64, w, h int) {
+	pix, w, h := premultiplied(img)
+	plane = make([]float64, w*h)
+	parallelRows(h, func(y0, y1 int) {
+		for i := y0 * w; i < y1*w; i++ {
+			p := pix[4*i : 4*i+3]
+			plane[i] = (0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])) / 257
+		}
+	})
+	return plane, w, h
+}
+
+// convolvePlane3 correlates a single channel with a 3x3 kernel, edges clamped.
+func convolvePlane3(plane []float64, w, h int, kernel [3][3]float64) []float64 {
+	out := make([]float64, len(plane))
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				var sum float64
+				for ky := 0; ky < 3; ky++ {
+					sy := min(max(y+ky-1, 0), h-1)
+					for kx := 0; kx < 3; kx++ {
+						if kernel[ky][kx] == 0 {
+							continue
+						}
+						sx := min(max(x+kx-1, 0), w-1)
+						sum += plane[sy*w+sx] * kernel[ky][kx]
+					}
+				}
+				out[y*w+x] = sum
+			}
+		}
+	})
+	return out
+}
+
+// blurPlane runs a separable 1-D kernel over a single channel, edges clamped.
+func blurPlane(plane []float64, w, h int, kernel []float64) []float64 {
+	radius := len(kernel) / 2
+	tmp := make([]float64, len(plane))
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				var sum float64
+				for k, weight := range kernel {
+					sum += plane[y*w+min(max(x+k-radius, 0), w-1)] * weight
+				}
+				tmp[y*w+x] = sum
+			}
+		}
+	})
+	out := make([]float64, len(plane))
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				var sum float64
+				for k, weight := range kernel {
+					sum += tmp[min(max(y+k-radius, 0), h-1)*w+x] * weight
+				}
+				out[y*w+x] = sum
+			}
+		}
+	})
+	return out
+}
+
+// imageGradient returns the X and Y derivative of the luminance for one of
+// the first-order operators.
+func imageGradient(plane []float64, w, h int, op EdgeOperator) (gx, gy []float64) {
+	kernels := gradientKernels[op]
+	return convolvePlane3(plane, w, h, kernels[0]), convolvePlane3(plane, w, h, kernels[1])
+}
+
+func maxAbs(values []float64) float64 {
+	var m float64
+	for _, v := range values {
+		m = max(m, math.Abs(v))
+	}
+	return m
+}
+
+// hsvToRgb converts hue in degrees and saturation and value in [0, 1].
+func hsvToRgb(h, s, v float64) (r, g, b float64) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return r + m, g + m, b + m
+}
+
+// detectEdges draws the chosen output of the operator. Magnitudes are drawn
+// as levels, or stretched so the strongest edge is white when normalising,
+// which keeps operators with larger kernel weights comparable; signed X/Y
+// components are drawn around mid-grey and the direction is the hue with the
+// magnitude as brightness. The alpha of the source is kept.
+func detectEdges(img image.Image, opts EdgeOptions) image.Image {
+	plane, w, h := luminancePlane(img)
+	var gx, gy, magnitude []float64
+	if laplacian, ok := laplacianKernels[opts.Operator]; ok {
+		magnitude = convolvePlane3(plane, w, h, laplacian)
+	} else if opts.Operator == edgeLoG {
+		blurred := blurPlane(plane, w, h, gaussianKernel1D(opts.Sigma, 0))
+		magnitude = convolvePlane3(blurred, w, h, laplacianKernels[edgeLaplacian8])
+	} else {
+		gx, gy = imageGradient(plane, w, h, opts.Operator)
+		magnitude = make([]float64, len(plane))
+		for i := range magnitude {
+			magnitude[i] = math.Hypot(gx[i], gy[i])
+		}
+	}
+	scale := 255.0
+	if opts.Normalize {
+		if scale = maxAbs(magnitude); scale == 0 {
+			scale = 1
+		}
+	}
+
+	b := img.Bounds()
+	// toNRGBA keeps the 8-bit alpha that RGBA() >> 8 gives
+	src := toNRGBA(img)
+	result := image.NewNRGBA(b)
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			srcRow := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < w; x++ {
+				i := y*w + x
+				var r, g, bl uint8
+				switch opts.Output {
+				case edgeX, edgeY:
+					component := gx[i]
+					if opts.Output == edgeY {
+						component = gy[i]
+					}
+					v := clampUint8(128 + 127*component/scale)
+					r, g, bl = v, v, v
+				case edgeDirection:
+					hue := math.Atan2(gy[i], gx[i]) * 180 / math.Pi
+					fr, fg, fb := hsvToRgb(hue, 1, min(1, math.Abs(magnitude[i])/scale))
+					r, g, bl = clampUint8(fr*255), clampUint8(fg*255), clampUint8(fb*255)
+				default:
+					v := clampUint8(255 * math.Abs(magnitude[i]) / scale)
+					r, g, bl = v, v, v
+				}
+				dst[4*x], dst[4*x+1], dst[4*x+2], dst[4*x+3] = r, g, bl, srcRow[4*x+3]
+			}
+		}
+	})
+	return result
+}
+
+func (a *App) HandleEdgeDetection(base64str string, opts EdgeOptions) string {
+	if err := opts.validate(); err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Invalid parameters",
+			Message:       err.Error(),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	return a.refFromImage(base64str, detectEdges(m, opts))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// verticalStep is black on the left half and white on the right half.
+func verticalStep() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 4; x < 8; x++ {
+			img.SetGray(x, y, color.Gray{255})
+		}
+	}
+	return img
+}
+
+func TestDetectEdgesVerticalStep(t *testing.T) {
+	img := verticalStep()
+	for op := range gradientKernels {
+		out := detectEdges(img, EdgeOptions{Operator: op, Output: edgeMagnitude})
+		// Roberts cross looks one pixel right and down, so its edge is at x=3
+		left, _, _, _ := out.At(3, 4).RGBA()
+		right, _, _, _ := out.At(4, 4).RGBA()
+		if max(left, right)>>8 != 255 {
+			t.Errorf("%s: magnitude on the edge = %d, want 255", op, max(left, right)>>8)
+		}
+		if v, _, _, _ := out.At(1, 4).RGBA(); v != 0 {
+			t.Errorf("%s: magnitude in a flat area = %d, want 0", op, v>>8)
+		}
+		// the step has no vertical component, except along Roberts' diagonals
+		if op != edgeRoberts {
+			out = detectEdges(img, EdgeOptions{Operator: op, Output: edgeY})
+			if v, _, _, _ := out.At(4, 4).RGBA(); v>>8 != 128 {
+				t.Errorf("%s: y component = %d, want 128", op, v>>8)
+			}
+		}
+	}
+}
+
+func TestDetectEdgesUsesLuminance(t *testing.T) {
+	// a green step is invisible to a red-only detector
+	img := image.NewRGBA(image.Rect(0, 0, 6, 6))
+	for y := 0; y < 6; y++ {
+		for x := 3; x < 6; x++ {
+			img.SetRGBA(x, y, color.RGBA{0, 255, 0, 255})
+		}
+	}
+	out := ApplySobelFilter(img)
+	if v, _, _, _ := out.At(3, 3).RGBA(); v == 0 {
+		t.Error("green edge was not detected")
+	}
+}
+
+func TestDetectEdgesNormalize(t *testing.T) {
+	// a faint step of 2 levels
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.SetGray(x, y, color.Gray{100 + 2*uint8(x/4)})
+		}
+	}
+	if v, _, _, _ := ApplySobelFilter(img).At(4, 4).RGBA(); v>>8 != 8 {
+		t.Errorf("sobel filter should draw the magnitude as it is, got %d, want 8", v>>8)
+	}
+	out := detectEdges(img, EdgeOptions{Operator: edgeSobel, Output: edgeMagnitude, Normalize: true})
+	if v, _, _, _ := out.At(4, 4).RGBA(); v>>8 != 255 {
+		t.Errorf("normalised, the strongest edge should be white, got %d", v>>8)
+	}
+}
+
+func TestEdgeOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts  EdgeOptions
+		valid bool
+	}{
+		{EdgeOptions{Operator: edgeScharr, Output: edgeDirection}, true},
+		{EdgeOptions{Operator: edgeLaplacian4, Output: edgeMagnitude}, true},
+		{EdgeOptions{Operator: edgeLaplacian8, Output: edgeX}, false},
+		{EdgeOptions{Operator: edgeLoG, Output: edgeMagnitude, Sigma: 1.4}, true},
+		{EdgeOptions{Operator: edgeLoG, Output: edgeMagnitude}, false},
+		{EdgeOptions{Operator: "canny", Output: edgeMagnitude}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v: got error %v", tt.opts, err)
+		}
+	}
+}
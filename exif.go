@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"log"
+	"strings"
+)
+
+// Exif holds the fields of the EXIF APP1 segment that are shown next to the
+// imported image. Zero values mean the tag was not present in the file.
+type Exif struct {
+	Make           string  `json:"make"`
+	Model          string  `json:"model"`
+	Orientation    int     `json:"orientation"`
+	ExposureTime   string  `json:"exposureTime"`
+	FNumber        float64 `json:"fNumber"`
+	ISO            int     `json:"iso"`
+	FocalLength    float64 `json:"focalLength"`
+	DateTime       string  `json:"dateTime"`
+	HasGPS         bool    `json:"hasGps"`
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	XResolution    float64 `json:"xResolution"`
+	YResolution    float64 `json:"yResolution"`
+	ResolutionUnit string  `json:"resolutionUnit"`
+}
+
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagXResolution      = 0x011a
+	tagYResolution      = 0x011b
+	tagResolutionUnit   = 0x0128
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagExposureTime     = 0x829a
+	tagFNumber          = 0x829d
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagFocalLength      = 0x920a
+	tagGPSLatitudeRef   = 0x0001
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitudeRef  = 0x0003
+	tagGPSLongitude     = 0x0004
+)
+
+var errNoExif = errors.New("no exif segment")
+
+// TIFF field types used by the tags read below
+const (
+	tiffByte      = 1
+	tiffAscii     = 2
+	tiffShort     = 3
+	tiffLong      = 4
+	tiffRational  = 5
+	tiffUndefined = 7
+	tiffSLong     = 9
+	tiffSRational = 10
+)
+
+var tiffTypeSizes = map[uint16]uint32{
+	tiffByte:      1,
+	tiffAscii:     1,
+	tiffShort:     2,
+	tiffLong:      4,
+	tiffRational:  8,
+	tiffUndefined: 1,
+	tiffSLong:     4,
+	tiffSRational: 8,
+}
+
+type tiffEntry struct {
+	typ   uint16
+	count uint32
+	raw   []byte
+}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// walkJpegSegments calls visit for each marker segment before the start of
+// scan with the segment offset and its payload, stopping early when visit
+// returns false.
+func walkJpegSegments(data []byte, visit func(marker byte, offset int, payload []byte) bool) error {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return errors.New("not a jpeg stream")
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return fmt.Errorf("invalid marker at offset %d", pos)
+		}
+		marker := data[pos+1]
+		if marker == 0xff {
+			pos++
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return fmt.Errorf("segment 0x%x overflows the file", marker)
+		}
+		if !visit(marker, pos, data[pos+4:pos+2+length]) {
+			return nil
+		}
+		pos += 2 + length
+	}
+	return nil
+}
+
+// jpegExifSegment returns the TIFF payload of the first Exif APP1 segment.
+func jpegExifSegment(data []byte) ([]byte, error) {
+	var tiff []byte
+	err := walkJpegSegments(data, func(marker byte, _ int, payload []byte) bool {
+		if marker == 0xe1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			tiff = payload[6:]
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tiff == nil {
+		return nil, errNoExif
+	}
+	return tiff, nil
+}
+
+func parseExif(data []byte) (*Exif, error) {
+	tiff, err := jpegExifSegment(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(tiff) < 8 {
+		return nil, errors.New("exif header too short")
+	}
+
+	tr := &tiffReader{data: tiff}
+	switch string(tiff[:2]) {
+	case "II":
+		tr.order = binary.LittleEndian
+	case "MM":
+		tr.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("unknown tiff byte order %q", tiff[:2])
+	}
+	if tr.order.Uint16(tiff[2:]) != 42 {
+		return nil, errors.New("invalid tiff magic number")
+	}
+
+	ifd0, err := tr.readIFD(tr.order.Uint32(tiff[4:]))
+	if err != nil {
+		return nil, err
+	}
+
+	exif := &Exif{Orientation: 1}
+	exif.Make = tr.str(ifd0[tagMake])
+	exif.Model = tr.str(ifd0[tagModel])
+	exif.DateTime = tr.str(ifd0[tagDateTime])
+	if o := tr.integer(ifd0[tagOrientation]); o >= 1 && o <= 8 {
+		exif.Orientation = int(o)
+	}
+	exif.XResolution = tr.rational(ifd0[tagXResolution], 0)
+	exif.YResolution = tr.rational(ifd0[tagYResolution], 0)
+	switch tr.integer(ifd0[tagResolutionUnit]) {
+	case 2:
+		exif.ResolutionUnit = "inch"
+	case 3:
+		exif.ResolutionUnit = "cm"
+	}
+
+	if e, ok := ifd0[tagExifIFD]; ok {
+		sub, err := tr.readIFD(tr.integer(e))
+		if err != nil {
+			return nil, fmt.Errorf("exif sub-ifd: %v", err)
+		}
+		if e, ok := sub[tagExposureTime]; ok {
+			exif.ExposureTime = tr.exposure(e)
+		}
+		exif.FNumber = tr.rational(sub[tagFNumber], 0)
+		exif.ISO = int(tr.integer(sub[tagISO]))
+		exif.FocalLength = tr.rational(sub[tagFocalLength], 0)
+		if original := tr.str(sub[tagDateTimeOriginal]); original != "" {
+			exif.DateTime = original
+		}
+	}
+
+	if e, ok := ifd0[tagGPSIFD]; ok {
+		gps, err := tr.readIFD(tr.integer(e))
+		if err != nil {
+			return nil, fmt.Errorf("gps ifd: %v", err)
+		}
+		lat, latOk := gps[tagGPSLatitude]
+		lon, lonOk := gps[tagGPSLongitude]
+		if latOk && lonOk {
+			exif.HasGPS = true
+			exif.Latitude = tr.degrees(lat)
+			exif.Longitude = tr.degrees(lon)
+			if tr.str(gps[tagGPSLatitudeRef]) == "S" {
+				exif.Latitude = -exif.Latitude
+			}
+			if tr.str(gps[tagGPSLongitudeRef]) == "W" {
+				exif.Longitude = -exif.Longitude
+			}
+		}
+	}
+
+	return exif, nil
+}
+
+func (tr *tiffReader) readIFD(offset uint32) (map[uint16]tiffEntry, error) {
+	if int(offset)+2 > len(tr.data) {
+		return nil, errors.New("ifd offset out of range")
+	}
+	count := int(tr.order.Uint16(tr.data[offset:]))
+	entries := make(map[uint16]tiffEntry, count)
+	pos := int(offset) + 2
+	for i := 0; i < count; i++ {
+		if pos+12 > len(tr.data) {
+			return nil, errors.New("ifd entry out of range")
+		}
+		tag := tr.order.Uint16(tr.data[pos:])
+		typ := tr.order.Uint16(tr.data[pos+2:])
+		n := tr.order.Uint32(tr.data[pos+4:])
+		pos += 12
+
+		size, known := tiffTypeSizes[typ]
+		if !known {
+			continue
+		}
+		total := uint64(size) * uint64(n)
+		var raw []byte
+		if total <= 4 {
+			raw = tr.data[pos-4 : pos-4+int(total)]
+		} else {
+			start := uint64(tr.order.Uint32(tr.data[pos-4:]))
+			if start+total > uint64(len(tr.data)) {
+				continue
+			}
+			raw = tr.data[start : start+total]
+		}
+		entries[tag] = tiffEntry{typ: typ, count: n, raw: raw}
+	}
+	return entries, nil
+}
+
+func (tr *tiffReader) str(e tiffEntry) string {
+	if e.typ != tiffAscii {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(e.raw), "\x00"))
+}
+
+func (tr *tiffReader) integer(e tiffEntry) uint32 {
+	if e.count == 0 {
+		return 0
+	}
+	switch e.typ {
+	case tiffByte, tiffUndefined:
+		return uint32(e.raw[0])
+	case tiffShort:
+		return uint32(tr.order.Uint16(e.raw))
+	case tiffLong, tiffSLong:
+		return tr.order.Uint32(e.raw)
+	}
+	return 0
+}
+
+func (tr *tiffReader) ratio(e tiffEntry, i int) (num, den int64) {
+	if uint32(i) >= e.count {
+		return 0, 0
+	}
+	switch e.typ {
+	case tiffRational:
+		return int64(tr.order.Uint32(e.raw[i*8:])), int64(tr.order.Uint32(e.raw[i*8+4:]))
+	case tiffSRational:
+		return int64(int32(tr.order.Uint32(e.raw[i*8:]))), int64(int32(tr.order.Uint32(e.raw[i*8+4:])))
+	}
+	return 0, 0
+}
+
+func (tr *tiffReader) rational(e tiffEntry, i int) float64 {
+	num, den := tr.ratio(e, i)
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
+// exposure formats the exposure time the way cameras print it, 1/250 s rather
+// than 0.004 s.
+func (tr *tiffReader) exposure(e tiffEntry) string {
+	num, den := tr.ratio(e, 0)
+	if num == 0 || den == 0 {
+		return ""
+	}
+	if num < den {
+		return fmt.Sprintf("1/%d", (den+num/2)/num)
+	}
+	return fmt.Sprintf("%g", float64(num)/float64(den))
+}
+
+func (tr *tiffReader) degrees(e tiffEntry) float64 {
+	return tr.rational(e, 0) + tr.rational(e, 1)/60 + tr.rational(e, 2)/3600
+}
+
+// orientImage maps the image so it is displayed upright according to the
+// EXIF Orientation tag (1-8).
+func orientImage(m image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return m
+	}
+	b := m.Bounds()
+	w, h := b.Dx(), b.Dy()
+	transposed := orientation >= 5
+	dstW, dstH := w, h
+	if transposed {
+		dstW, dstH = h, w
+	}
+	oriented := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			oriented.Set(x, y, color.RGBAModel.Convert(m.At(b.Min.X+sx, b.Min.Y+sy)))
+		}
+	}
+	return oriented
+}
+
+// decodeJpegWithExif decodes the JPEG and rotates it upright. A file without
+// an EXIF segment is not an error, the returned Exif is nil then.
+func decodeJpegWithExif(data []byte) (image.Image, *Exif, error) {
+	m, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	exif, err := parseExif(data)
+	if err != nil {
+		if !errors.Is(err, errNoExif) {
+			log.Println("skipping exif:", err)
+		}
+		return m, nil, nil
+	}
+	return orientImage(m, exif.Orientation), exif, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// jpegWithExif builds a little-endian EXIF APP1 segment with Make,
+// Orientation and a GPS latitude/longitude, and splices it after SOI.
+func jpegWithExif(t *testing.T, m image.Image, orientation uint16) []byte {
+	t.Helper()
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, m, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	le := binary.LittleEndian
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	entry := func(tag, typ uint16, count, value uint32) []byte {
+		e := make([]byte, 12)
+		le.PutUint16(e, tag)
+		le.PutUint16(e[2:], typ)
+		le.PutUint32(e[4:], count)
+		le.PutUint32(e[8:], value)
+		return e
+	}
+	// IFD0 at 8: 3 entries, next IFD offset, then "Canon\0" at 50
+	ifd0 := []byte{3, 0}
+	ifd0 = append(ifd0, entry(tagMake, tiffAscii, 6, 50)...)
+	ifd0 = append(ifd0, entry(tagOrientation, tiffShort, 1, uint32(orientation))...)
+	ifd0 = append(ifd0, entry(tagGPSIFD, tiffLong, 1, 56)...)
+	ifd0 = append(ifd0, 0, 0, 0, 0)
+	tiff = append(tiff, ifd0...)
+	tiff = append(tiff, []byte("Canon\x00")...)
+	// GPS IFD at 56: 4 entries, rationals at 110 and 134
+	gps := []byte{4, 0}
+	gps = append(gps, entry(tagGPSLatitudeRef, tiffAscii, 2, 'N')...)
+	gps = append(gps, entry(tagGPSLatitude, tiffRational, 3, 110)...)
+	gps = append(gps, entry(tagGPSLongitudeRef, tiffAscii, 2, 'W')...)
+	gps = append(gps, entry(tagGPSLongitude, tiffRational, 3, 134)...)
+	gps = append(gps, 0, 0, 0, 0)
+	tiff = append(tiff, gps...)
+	for _, r := range []uint32{53, 1, 30, 1, 0, 1, 23, 1, 15, 1, 0, 1} {
+		tiff = le.AppendUint32(tiff, r)
+	}
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg.Bytes()[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg.Bytes()[2:]...)
+}
+
+func TestDecodeJpegWithExif(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			m.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+
+	oriented, exif, err := decodeJpegWithExif(jpegWithExif(t, m, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exif == nil {
+		t.Fatal("exif was not parsed")
+	}
+	if exif.Make != "Canon" || exif.Orientation != 6 {
+		t.Errorf("got make %q orientation %d", exif.Make, exif.Orientation)
+	}
+	if !exif.HasGPS || exif.Latitude < 53.49 || exif.Latitude > 53.51 || exif.Longitude > -23.24 {
+		t.Errorf("got gps %v %f %f", exif.HasGPS, exif.Latitude, exif.Longitude)
+	}
+	if b := oriented.Bounds(); b.Dx() != 8 || b.Dy() != 16 {
+		t.Errorf("orientation 6 should swap dimensions, got %v", b)
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	m.Set(0, 0, color.RGBA{255, 0, 0, 255})
+
+	tests := []struct {
+		orientation int
+		x, y        int
+	}{
+		{2, 2, 0},
+		{3, 2, 1},
+		{4, 0, 1},
+		{5, 0, 0},
+		{6, 1, 0},
+		{7, 1, 2},
+		{8, 0, 2},
+	}
+	for _, tt := range tests {
+		oriented := orientImage(m, tt.orientation)
+		r, _, _, _ := oriented.At(tt.x, tt.y).RGBA()
+		if r>>8 != 255 {
+			t.Errorf("orientation %d: top-left pixel not found at (%d,%d)", tt.orientation, tt.x, tt.y)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// FrequencyChannels chooses whether the luminance or every colour channel is
+// transformed. Luminance results are gray.
+type FrequencyChannels string
+
+const (
+	frequencyLuminance FrequencyChannels = "luminance"
+	frequencyRgb       FrequencyChannels = "rgb"
+)
+
+var AllFrequencyChannels = []struct {
+	Value  FrequencyChannels
+	TSName string
+}{
+	{frequencyLuminance, "luminance"},
+	{frequencyRgb, "rgb"},
+}
+
+type SpectrumKind string
+
+const (
+	spectrumMagnitude SpectrumKind = "magnitude"
+	spectrumPhase     SpectrumKind = "phase"
+)
+
+var AllSpectrumKinds = []struct {
+	Value  SpectrumKind
+	TSName string
+}{
+	{spectrumMagnitude, "magnitude"},
+	{spectrumPhase, "phase"},
+}
+
+type FilterShape string
+
+const (
+	shapeIdeal       FilterShape = "ideal"
+	shapeButterworth FilterShape = "butterworth"
+	shapeGaussian    FilterShape = "gaussian"
+)
+
+var AllFilterShapes = []struct {
+	Value  FilterShape
+	TSName string
+}{
+	{shapeIdeal, "ideal"},
+	{shapeButterworth, "butterworth"},
+	{shapeGaussian, "gaussian"},
+}
+
+type FrequencyPass string
+
+const (
+	passLow   FrequencyPass = "lowpass"
+	passHigh  FrequencyPass = "highpass"
+	passBand  FrequencyPass = "bandpass"
+	passNotch FrequencyPass = "notch"
+)
+
+var AllFrequencyPasses = []struct {
+	Value  FrequencyPass
+	TSName string
+}{
+	{passLow, "lowpass"},
+	{passHigh, "highpass"},
+	{passBand, "bandpass"},
+	{passNotch, "notch"},
+}
+
+// NotchPoint is a frequency to reject, in the same normalised units as the
+// cutoff with (0, 0) in the centre of the spectrum image and u to the right.
+// Its mirror (-u, -v) is rejected as well.
+type NotchPoint struct {
+	U float64 `json:"u"`
+	V float64 `json:"v"`
+}
+
+// parseNotches reads notch frequencies written as "u,v u,v ...".
+func parseNotches(s string) ([]NotchPoint, error) {
+	var notches []NotchPoint
+	for _, field := range strings.Fields(s) {
+		us, vs, ok := strings.Cut(field, ",")
+		u, errU := strconv.ParseFloat(us, 64)
+		v, errV := strconv.ParseFloat(vs, 64)
+		if !ok || errU != nil || errV != nil || math.IsNaN(u) || math.IsNaN(v) {
+			return nil, fmt.Errorf("notch '%s' is not of the form u,v", field)
+		}
+		notches = append(notches, NotchPoint{u, v})
+	}
+	return notches, nil
+}
+
+// FrequencyFilterOptions configures HandleFrequencyFilter. Frequencies are
+// normalised so 1 is the Nyquist frequency, the edge of the spectrum image.
+// Cutoff is the radius of low- and high-pass filters, the centre of the band
+// and the radius of every notch. Width is the width of the band and Order
+// the Butterworth order.
+type FrequencyFilterOptions struct {
+	Pass     FrequencyPass     `json:"pass"`
+	Shape    FilterShape       `json:"shape"`
+	Cutoff   float64           `json:"cutoff"`
+	Width    float64           `json:"width"`
+	Order    int               `json:"order"`
+	Notches  []NotchPoint      `json:"notches"`
+	Channels FrequencyChannels `json:"channels"`
+}
+
+func (o FrequencyFilterOptions) validate() error {
+	switch o.Pass {
+	case passLow, passHigh, passNotch:
+	case passBand:
+		if o.Width <= 0 {
+			return fmt.Errorf("band width must be positive, got %g", o.Width)
+		}
+	default:
+		return fmt.Errorf("unknown filter type '%s'", o.Pass)
+	}
+	if o.Pass == passNotch && len(o.Notches) == 0 {
+		return fmt.Errorf("notch filter needs at least one frequency to reject")
+	}
+	switch o.Shape {
+	case shapeIdeal, shapeGaussian:
+	case shapeButterworth:
+		if o.Order < 1 {
+			return fmt.Errorf("butterworth order must be at least 1, got %d", o.Order)
+		}
+	default:
+		return fmt.Errorf("unknown filter shape '%s'", o.Shape)
+	}
+	if o.Cutoff <= 0 {
+		return fmt.Errorf("cutoff must be positive, got %g", o.Cutoff)
+	}
+	if o.Channels != frequencyLuminance && o.Channels != frequencyRgb {
+		return fmt.Errorf("unknown channels '%s'", o.Channels)
+	}
+	return nil
+}
+
+// lowPass is the low-pass transfer function of the shape at distance d.
+func (o FrequencyFilterOptions) lowPass(d, cutoff float64) float64 {
+	switch o.Shape {
+	case shapeButterworth:
+		return 1 / (1 + math.Pow(d/cutoff, float64(2*o.Order)))
+	case shapeGaussian:
+		return math.Exp(-d * d / (2 * cutoff * cutoff))
+	default:
+		if d <= cutoff {
+			return 1
+		}
+		return 0
+	}
+}
+
+// bandPass passes frequencies within Width/2 of Cutoff.
+func (o FrequencyFilterOptions) bandPass(d float64) float64 {
+	switch o.Shape {
+	case shapeButterworth:
+		if d == 0 {
+			return 0
+		}
+		ratio := d * o.Width / (d*d - o.Cutoff*o.Cutoff)
+		return 1 - 1/(1+math.Pow(ratio, float64(2*o.Order)))
+	case shapeGaussian:
+		if d == 0 {
+			return 0
+		}
+		ratio := (d*d - o.Cutoff*o.Cutoff) / (d * o.Width)
+		return math.Exp(-ratio * ratio)
+	default:
+		if math.Abs(d-o.Cutoff) <= o.Width/2 {
+			return 1
+		}
+		return 0
+	}
+}
+
+// transfer returns the filter gain at normalised frequency (u, v).
+func (o FrequencyFilterOptions) transfer(u, v float64) float64 {
+	d := math.Hypot(u, v)
+	switch o.Pass {
+	case passHigh:
+		return 1 - o.lowPass(d, o.Cutoff)
+	case passBand:
+		return o.bandPass(d)
+	case passNotch:
+		gain := 1.0
+		for _, n := range o.Notches {
+			gain *= 1 - o.lowPass(math.Hypot(u-n.U, v-n.V), o.Cutoff)
+			gain *= 1 - o.lowPass(math.Hypot(u+n.U, v+n.V), o.Cutoff)
+		}
+		return gain
+	default:
+		return o.lowPass(d, o.Cutoff)
+	}
+}
+
+// fft1D is an in-place iterative radix-2 transform, len(data) must be a power
+// of two. The inverse is scaled by 1/n.
+func fft1D(data []complex128, inverse bool) {
+	n := len(data)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := data[start+k], data[start+k+size/2]*w
+				data[start+k] = even + odd
+				data[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+	if inverse {
+		for i := range data {
+			data[i] /= complex(float64(n), 0)
+		}
+	}
+}
+
+// spectrum is the 2-D transform of one channel padded to power-of-two sizes.
+type spectrum struct {
+	w, h int
+	data []complex128
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fft2D transforms a channel of size w x h. The padding repeats the edge
+// pixels, which causes less ringing than padding with zeros.
+func fft2D(plane []float64, w, h int) *spectrum {
+	s := &spectrum{w: nextPowerOfTwo(w), h: nextPowerOfTwo(h)}
+	s.data = make([]complex128, s.w*s.h)
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			s.data[y*s.w+x] = complex(plane[min(y, h-1)*w+min(x, w-1)], 0)
+		}
+	}
+	s.transform(false)
+	return s
+}
+
+func (s *spectrum) transform(inverse bool) {
+	parallelRows(s.h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			fft1D(s.data[y*s.w:(y+1)*s.w], inverse)
+		}
+	})
+	// columns are independent too, each range gets its own buffer
+	parallelRows(s.w, func(x0, x1 int) {
+		column := make([]complex128, s.h)
+		for x := x0; x < x1; x++ {
+			for y := 0; y < s.h; y++ {
+				column[y] = s.data[y*s.w+x]
+			}
+			fft1D(column, inverse)
+			for y := 0; y < s.h; y++ {
+				s.data[y*s.w+x] = column[y]
+			}
+		}
+	})
+}
+
+// frequency returns the normalised frequency of a transform index, with
+// negative frequencies in the upper half.
+func frequency(k, n int) float64 {
+	if k >= n/2 {
+		k -= n
+	}
+	return 2 * float64(k) / float64(n)
+}
+
+func (s *spectrum) apply(opts FrequencyFilterOptions) {
+	parallelRows(s.h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			v := frequency(y, s.h)
+			for x := 0; x < s.w; x++ {
+				s.data[y*s.w+x] *= complex(opts.transfer(frequency(x, s.w), v), 0)
+			}
+		}
+	})
+}
+
+// inverse transforms back and crops the padding.
+func (s *spectrum) inverse(w, h int) []float64 {
+	s.transform(true)
+	plane := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			plane[y*w+x] = real(s.data[y*s.w+x])
+		}
+	}
+	return plane
+}
+
+// view renders the spectrum with the zero frequency in the centre, either
+// as log(1 + |F|) stretched to 0-255 or as the phase mapped from -pi..pi.
+func (s *spectrum) view(kind SpectrumKind) []float64 {
+	out := make([]float64, len(s.data))
+	var peak float64
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			c := s.data[((y+s.h/2)%s.h)*s.w+(x+s.w/2)%s.w]
+			v := math.Log1p(cmplx.Abs(c))
+			if kind == spectrumPhase {
+				v = (cmplx.Phase(c) + math.Pi) / (2 * math.Pi) * 255
+			}
+			out[y*s.w+x] = v
+			peak = max(peak, v)
+		}
+	}
+	if kind == spectrumMagnitude && peak > 0 {
+		for i := range out {
+			out[i] *= 255 / peak
+		}
+	}
+	return out
+}
+
+// frequencyPlanes returns the channels to transform and the source alpha.
+func frequencyPlanes(img image.Image, channels FrequencyChannels) (planes [][]float64, alpha []float64, w, h int) {
+	rgba, w, h := nrgbaPlanes(img)
+	if channels == frequencyLuminance {
+		lum := make([]float64, w*h)
+		for i := range lum {
+			lum[i] = luma(rgba[0][i], rgba[1][i], rgba[2][i])
+		}
+		return [][]float64{lum}, rgba[3], w, h
+	}
+	return rgba[:3], rgba[3], w, h
+}
+
+// planesImage builds an image from one gray plane or three colour planes.
+func planesImage(planes [][]float64, alpha []float64, bounds image.Rectangle) *image.NRGBA {
+	var rgba [4][]float64
+	for c := 0; c < 3; c++ {
+		rgba[c] = planes[min(c, len(planes)-1)]
+	}
+	rgba[3] = alpha
+	if alpha == nil {
+		rgba[3] = make([]float64, len(planes[0]))
+		for i := range rgba[3] {
+			rgba[3][i] = 255
+		}
+	}
+	return nrgbaFromPlanes(rgba, bounds)
+}
+
+// spectrumImage returns the centred spectrum of the image. It has the padded
+// power-of-two size, not the size of the source.
+func spectrumImage(img image.Image, kind SpectrumKind, channels FrequencyChannels) image.Image {
+	planes, _, w, h := frequencyPlanes(img, channels)
+	views := make([][]float64, len(planes))
+	var s *spectrum
+	for c, plane := range planes {
+		s = fft2D(plane, w, h)
+		views[c] = s.view(kind)
+	}
+	return planesImage(views, nil, image.Rect(0, 0, s.w, s.h))
+}
+
+// frequencyFilter multiplies the spectrum of every channel with the filter
+// and transforms it back. Alpha is kept.
+func frequencyFilter(img image.Image, opts FrequencyFilterOptions) image.Image {
+	planes, alpha, w, h := frequencyPlanes(img, opts.Channels)
+	filtered := make([][]float64, len(planes))
+	for c, plane := range planes {
+		s := fft2D(plane, w, h)
+		s.apply(opts)
+		filtered[c] = s.inverse(w, h)
+	}
+	return planesImage(filtered, alpha, img.Bounds())
+}
+
+func (a *App) HandleSpectrum(base64str string, kind SpectrumKind, channels FrequencyChannels) string {
+	if (kind != spectrumMagnitude && kind != spectrumPhase) ||
+		(channels != frequencyLuminance && channels != frequencyRgb) {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Invalid parameters",
+			Message:       "Spectrum must be magnitude or phase of the luminance or rgb channels",
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	// the spectrum is a view of the image, not an edit, so the selection
+	// does not apply
+	return a.encodeLike(base64str, spectrumImage(m, kind, channels))
+}
+
+func (a *App) HandleFrequencyFilter(base64str string, opts FrequencyFilterOptions) string {
+	if err := opts.validate(); err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Invalid parameters",
+			Message:       err.Error(),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	return a.refFromImage(base64str, frequencyFilter(m, opts))
+}
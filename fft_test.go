@@ -0,0 +1,102 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFFTRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	w, h := 13, 7
+	plane := make([]float64, w*h)
+	for i := range plane {
+		plane[i] = rng.Float64() * 255
+	}
+	back := fft2D(plane, w, h).inverse(w, h)
+	for i := range plane {
+		if math.Abs(plane[i]-back[i]) > 1e-9 {
+			t.Fatalf("value %d: got %g, want %g", i, back[i], plane[i])
+		}
+	}
+}
+
+// stripes returns a gray image with vertical stripes of the given period.
+func stripes(w, h, period int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := 128 + 100*math.Cos(2*math.Pi*float64(x)/float64(period))
+			img.SetGray(x, y, color.Gray{uint8(math.Round(v))})
+		}
+	}
+	return img
+}
+
+func TestFrequencyFilter(t *testing.T) {
+	img := stripes(32, 32, 4)
+	// the stripes sit at half the Nyquist frequency, without DC the dark half
+	// of the stripes clamps to 0
+	tests := []struct {
+		opts    FrequencyFilterOptions
+		striped bool
+	}{
+		{FrequencyFilterOptions{Pass: passLow, Shape: shapeIdeal, Cutoff: 0.25}, false},
+		{FrequencyFilterOptions{Pass: passLow, Shape: shapeIdeal, Cutoff: 0.75}, true},
+		{FrequencyFilterOptions{Pass: passHigh, Shape: shapeGaussian, Cutoff: 0.05}, true},
+		{FrequencyFilterOptions{Pass: passBand, Shape: shapeButterworth, Cutoff: 0.5, Width: 0.2, Order: 2}, true},
+		{FrequencyFilterOptions{Pass: passNotch, Shape: shapeIdeal, Cutoff: 0.05, Notches: []NotchPoint{{0.5, 0}}}, false},
+	}
+	for _, tt := range tests {
+		tt.opts.Channels = frequencyLuminance
+		if err := tt.opts.validate(); err != nil {
+			t.Fatal(err)
+		}
+		out := frequencyFilter(img, tt.opts)
+		a, _, _, _ := out.At(0, 5).RGBA()
+		b, _, _, _ := out.At(2, 5).RGBA()
+		if striped := int(a>>8)-int(b>>8) > 90; striped != tt.striped {
+			t.Errorf("%s %s: stripes kept = %v (%d, %d), want %v",
+				tt.opts.Shape, tt.opts.Pass, striped, a>>8, b>>8, tt.striped)
+		}
+	}
+}
+
+func TestSpectrumImage(t *testing.T) {
+	img := stripes(32, 32, 4)
+	out := spectrumImage(img, spectrumMagnitude, frequencyLuminance)
+	// DC in the centre and the two stripe peaks at +-8 cycles
+	for _, p := range []image.Point{{16, 16}, {8, 16}, {24, 16}} {
+		if v, _, _, _ := out.At(p.X, p.Y).RGBA(); v>>8 < 200 {
+			t.Errorf("expected a peak at %v, got %d", p, v>>8)
+		}
+	}
+	if v, _, _, _ := out.At(16, 8).RGBA(); v>>8 > 20 {
+		t.Errorf("unexpected energy at (16,8): %d", v>>8)
+	}
+}
+
+func TestSpectrumIgnoresSelection(t *testing.T) {
+	a := NewApp()
+	img := image.NewGray(image.Rect(0, 0, 256, 256))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+	if err := a.SetSelection(SelectionMask{Kind: maskRectangle, X: 0, Y: 0, Width: 128, Height: 256}); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []SpectrumKind{spectrumMagnitude, spectrumPhase} {
+		m, err := a.imageFromRef(a.HandleSpectrum(a.images.put(img), kind, frequencyLuminance))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := spectrumImage(img, kind, frequencyLuminance)
+		for _, p := range []image.Point{{10, 100}, {200, 100}} {
+			if got, exp := color.NRGBAModel.Convert(m.At(p.X, p.Y)), color.NRGBAModel.Convert(want.At(p.X, p.Y)); got != exp {
+				t.Errorf("%s at %v: got %v, want %v", kind, p, got, exp)
+			}
+		}
+	}
+}
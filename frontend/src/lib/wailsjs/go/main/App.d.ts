@@ -2,10 +2,18 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function ApplyOperation(arg1:string,arg2:string,arg3:{[key: string]: any}):Promise<string>;
+
+export function ClearSelection():Promise<void>;
+
 export function CmykToRgb(arg1:number,arg2:number,arg3:number,arg4:number):Promise<main.Rgb>;
 
 export function HandleAlphaPointWiseTransformations(arg1:number,arg2:string):Promise<string>;
 
+export function HandleAnisotropicDiffusion(arg1:string,arg2:number,arg3:number,arg4:main.Conduction):Promise<string>;
+
+export function HandleBilateralFilter(arg1:string,arg2:number,arg3:number):Promise<string>;
+
 export function HandleBinarizeBernsen(arg1:string,arg2:number,arg3:number):Promise<string>;
 
 export function HandleBinarizeManual(arg1:string,arg2:number):Promise<string>;
@@ -18,26 +26,80 @@ export function HandleBinarizeOtsu(arg1:string):Promise<string>;
 
 export function HandleBinarizePercentBlack(arg1:string,arg2:number):Promise<string>;
 
+export function HandleBitPlanes(arg1:string):Promise<Array<string>>;
+
+export function HandleBlend(arg1:string,arg2:string,arg3:main.BlendOptions):Promise<string>;
+
+export function HandleCanny(arg1:string,arg2:main.CannyOptions):Promise<string>;
+
 export function HandleClosing(arg1:string):Promise<string>;
 
+export function HandleCombineBitPlanes(arg1:string,arg2:Array<string>):Promise<string>;
+
+export function HandleConvolution(arg1:string,arg2:main.ConvolutionOptions):Promise<string>;
+
+export function HandleCurves(arg1:string,arg2:main.CurvesOptions):Promise<string>;
+
 export function HandleDilation(arg1:string):Promise<string>;
 
+export function HandleEdgeDetection(arg1:string,arg2:main.EdgeOptions):Promise<string>;
+
 export function HandleErosion(arg1:string):Promise<string>;
 
 export function HandleFilterApplying(arg1:string):Promise<string>;
 
+export function HandleFrequencyFilter(arg1:string,arg2:main.FrequencyFilterOptions):Promise<string>;
+
+export function HandleGaussianBlur(arg1:string,arg2:number,arg3:number):Promise<string>;
+
 export function HandleGrassTask(arg1:string,arg2:number):Promise<string>;
 
+export function HandleGrayscale(arg1:string,arg2:main.GrayOptions):Promise<string>;
+
 export function HandleHistogram(arg1:string):Promise<string>;
 
 export function HandleHitOrMiss(arg1:string):Promise<string>;
 
+export function HandleHueSaturation(arg1:string,arg2:main.HueSaturationOptions):Promise<string>;
+
+export function HandleInvert(arg1:string):Promise<string>;
+
+export function HandleKuwaharaFilter(arg1:string,arg2:number):Promise<string>;
+
+export function HandleLevels(arg1:string,arg2:main.LevelsOptions):Promise<string>;
+
+export function HandleMedianFilter(arg1:string,arg2:number,arg3:main.MedianShape):Promise<string>;
+
+export function HandleNoise(arg1:string,arg2:main.NoiseOptions):Promise<string>;
+
 export function HandleOpening(arg1:string):Promise<string>;
 
+export function HandlePosterize(arg1:string,arg2:number):Promise<string>;
+
 export function HandleRgbPointWiseTransformations(arg1:Array<string>,arg2:string):Promise<string>;
 
+export function HandleSharpen(arg1:string,arg2:main.SharpenOptions):Promise<string>;
+
+export function HandleSolarize(arg1:string,arg2:number):Promise<string>;
+
+export function HandleSpectrum(arg1:string,arg2:main.SpectrumKind,arg3:main.FrequencyChannels):Promise<string>;
+
 export function HandleToGrayPointWiseTransformations(arg1:string,arg2:string):Promise<string>;
 
+export function HandleToneTransform(arg1:string,arg2:main.ToneOptions):Promise<string>;
+
+export function ListOperations():Promise<Array<main.OperationInfo>>;
+
+export function RegisterImage(arg1:string):Promise<string>;
+
+export function ReleaseImage(arg1:string):Promise<void>;
+
 export function RgbToCmyk(arg1:number,arg2:number,arg3:number):Promise<main.Cmyk>;
 
-export function SaveCanvasImg(arg1:string,arg2:main.ImageFormat,arg3:Array<string>):Promise<void>;
+export function SaveCanvasImg(arg1:string,arg2:main.ImageFormat,arg3:Array<main.MetadataEntry>):Promise<void>;
+
+export function SaveImageTo(arg1:string,arg2:string,arg3:main.ImageFormat,arg4:main.SaveOptions):Promise<void>;
+
+export function SetSelection(arg1:main.SelectionMask):Promise<void>;
+
+export function ToneTransferCurve(arg1:string,arg2:main.ToneOptions):Promise<Array<number>>;
@@ -2,6 +2,14 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ApplyOperation(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ApplyOperation'](arg1, arg2, arg3);
+}
+
+export function ClearSelection() {
+  return window['go']['main']['App']['ClearSelection']();
+}
+
 export function CmykToRgb(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['CmykToRgb'](arg1, arg2, arg3, arg4);
 }
@@ -10,6 +18,14 @@ export function HandleAlphaPointWiseTransformations(arg1, arg2) {
   return window['go']['main']['App']['HandleAlphaPointWiseTransformations'](arg1, arg2);
 }
 
+export function HandleAnisotropicDiffusion(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['HandleAnisotropicDiffusion'](arg1, arg2, arg3, arg4);
+}
+
+export function HandleBilateralFilter(arg1, arg2, arg3) {
+  return window['go']['main']['App']['HandleBilateralFilter'](arg1, arg2, arg3);
+}
+
 export function HandleBinarizeBernsen(arg1, arg2, arg3) {
   return window['go']['main']['App']['HandleBinarizeBernsen'](arg1, arg2, arg3);
 }
@@ -34,14 +50,42 @@ export function HandleBinarizePercentBlack(arg1, arg2) {
   return window['go']['main']['App']['HandleBinarizePercentBlack'](arg1, arg2);
 }
 
+export function HandleBitPlanes(arg1) {
+  return window['go']['main']['App']['HandleBitPlanes'](arg1);
+}
+
+export function HandleBlend(arg1, arg2, arg3) {
+  return window['go']['main']['App']['HandleBlend'](arg1, arg2, arg3);
+}
+
+export function HandleCanny(arg1, arg2) {
+  return window['go']['main']['App']['HandleCanny'](arg1, arg2);
+}
+
 export function HandleClosing(arg1) {
   return window['go']['main']['App']['HandleClosing'](arg1);
 }
 
+export function HandleCombineBitPlanes(arg1, arg2) {
+  return window['go']['main']['App']['HandleCombineBitPlanes'](arg1, arg2);
+}
+
+export function HandleConvolution(arg1, arg2) {
+  return window['go']['main']['App']['HandleConvolution'](arg1, arg2);
+}
+
+export function HandleCurves(arg1, arg2) {
+  return window['go']['main']['App']['HandleCurves'](arg1, arg2);
+}
+
 export function HandleDilation(arg1) {
   return window['go']['main']['App']['HandleDilation'](arg1);
 }
 
+export function HandleEdgeDetection(arg1, arg2) {
+  return window['go']['main']['App']['HandleEdgeDetection'](arg1, arg2);
+}
+
 export function HandleErosion(arg1) {
   return window['go']['main']['App']['HandleErosion'](arg1);
 }
@@ -50,10 +94,22 @@ export function HandleFilterApplying(arg1) {
   return window['go']['main']['App']['HandleFilterApplying'](arg1);
 }
 
+export function HandleFrequencyFilter(arg1, arg2) {
+  return window['go']['main']['App']['HandleFrequencyFilter'](arg1, arg2);
+}
+
+export function HandleGaussianBlur(arg1, arg2, arg3) {
+  return window['go']['main']['App']['HandleGaussianBlur'](arg1, arg2, arg3);
+}
+
 export function HandleGrassTask(arg1, arg2) {
   return window['go']['main']['App']['HandleGrassTask'](arg1, arg2);
 }
 
+export function HandleGrayscale(arg1, arg2) {
+  return window['go']['main']['App']['HandleGrayscale'](arg1, arg2);
+}
+
 export function HandleHistogram(arg1) {
   return window['go']['main']['App']['HandleHistogram'](arg1);
 }
@@ -62,18 +118,74 @@ export function HandleHitOrMiss(arg1) {
   return window['go']['main']['App']['HandleHitOrMiss'](arg1);
 }
 
+export function HandleHueSaturation(arg1, arg2) {
+  return window['go']['main']['App']['HandleHueSaturation'](arg1, arg2);
+}
+
+export function HandleInvert(arg1) {
+  return window['go']['main']['App']['HandleInvert'](arg1);
+}
+
+export function HandleKuwaharaFilter(arg1, arg2) {
+  return window['go']['main']['App']['HandleKuwaharaFilter'](arg1, arg2);
+}
+
+export function HandleLevels(arg1, arg2) {
+  return window['go']['main']['App']['HandleLevels'](arg1, arg2);
+}
+
+export function HandleMedianFilter(arg1, arg2, arg3) {
+  return window['go']['main']['App']['HandleMedianFilter'](arg1, arg2, arg3);
+}
+
+export function HandleNoise(arg1, arg2) {
+  return window['go']['main']['App']['HandleNoise'](arg1, arg2);
+}
+
 export function HandleOpening(arg1) {
   return window['go']['main']['App']['HandleOpening'](arg1);
 }
 
+export function HandlePosterize(arg1, arg2) {
+  return window['go']['main']['App']['HandlePosterize'](arg1, arg2);
+}
+
 export function HandleRgbPointWiseTransformations(arg1, arg2) {
   return window['go']['main']['App']['HandleRgbPointWiseTransformations'](arg1, arg2);
 }
 
+export function HandleSharpen(arg1, arg2) {
+  return window['go']['main']['App']['HandleSharpen'](arg1, arg2);
+}
+
+export function HandleSolarize(arg1, arg2) {
+  return window['go']['main']['App']['HandleSolarize'](arg1, arg2);
+}
+
+export function HandleSpectrum(arg1, arg2, arg3) {
+  return window['go']['main']['App']['HandleSpectrum'](arg1, arg2, arg3);
+}
+
 export function HandleToGrayPointWiseTransformations(arg1, arg2) {
   return window['go']['main']['App']['HandleToGrayPointWiseTransformations'](arg1, arg2);
 }
 
+export function HandleToneTransform(arg1, arg2) {
+  return window['go']['main']['App']['HandleToneTransform'](arg1, arg2);
+}
+
+export function ListOperations() {
+  return window['go']['main']['App']['ListOperations']();
+}
+
+export function RegisterImage(arg1) {
+  return window['go']['main']['App']['RegisterImage'](arg1);
+}
+
+export function ReleaseImage(arg1) {
+  return window['go']['main']['App']['ReleaseImage'](arg1);
+}
+
 export function RgbToCmyk(arg1, arg2, arg3) {
   return window['go']['main']['App']['RgbToCmyk'](arg1, arg2, arg3);
 }
@@ -81,3 +193,15 @@ export function RgbToCmyk(arg1, arg2, arg3) {
 export function SaveCanvasImg(arg1, arg2, arg3) {
   return window['go']['main']['App']['SaveCanvasImg'](arg1, arg2, arg3);
 }
+
+export function SaveImageTo(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SaveImageTo'](arg1, arg2, arg3, arg4);
+}
+
+export function SetSelection(arg1) {
+  return window['go']['main']['App']['SetSelection'](arg1);
+}
+
+export function ToneTransferCurve(arg1, arg2) {
+  return window['go']['main']['App']['ToneTransferCurve'](arg1, arg2);
+}
@@ -3,4 +3,10 @@
 
 export function GetJobStatus(arg1:string):Promise<string>;
 
+export function ImportFile(arg1:string):Promise<string>;
+
+export function RecentFiles():Promise<Array<string>>;
+
+export function UnwatchFile(arg1:string):Promise<void>;
+
 export function UploadNetPbmImg():Promise<string>;
@@ -6,6 +6,18 @@ export function GetJobStatus(arg1) {
   return window['go']['main']['Worker']['GetJobStatus'](arg1);
 }
 
+export function ImportFile(arg1) {
+  return window['go']['main']['Worker']['ImportFile'](arg1);
+}
+
+export function RecentFiles() {
+  return window['go']['main']['Worker']['RecentFiles']();
+}
+
+export function UnwatchFile(arg1) {
+  return window['go']['main']['Worker']['UnwatchFile'](arg1);
+}
+
 export function UploadNetPbmImg() {
   return window['go']['main']['Worker']['UploadNetPbmImg']();
 }
@@ -1,7 +1,91 @@
 export namespace main {
 	
+	export enum BlendMode {
+	    add = "add",
+	    subtract = "subtract",
+	    difference = "difference",
+	    multiply = "multiply",
+	    divide = "divide",
+	    min = "min",
+	    max = "max",
+	    average = "average",
+	    and = "and",
+	    or = "or",
+	    xor = "xor",
+	    normal = "normal",
+	    screen = "screen",
+	    overlay = "overlay",
+	    softlight = "softlight",
+	    darken = "darken",
+	    lighten = "lighten",
+	}
+	export enum BorderMode {
+	    clamp = "clamp",
+	    wrap = "wrap",
+	    reflect = "reflect",
+	    constant = "constant",
+	}
+	export enum CannyThresholds {
+	    manual = "manual",
+	    median = "median",
+	    otsu = "otsu",
+	}
+	export enum ColorModel {
+	    hsv = "hsv",
+	    hsl = "hsl",
+	}
+	export enum Conduction {
+	    exponential = "exponential",
+	    quadratic = "quadratic",
+	}
+	export enum ContrastPivot {
+	    midgrey = "midgrey",
+	    mean = "mean",
+	}
+	export enum EdgeOperator {
+	    sobel = "sobel",
+	    prewitt = "prewitt",
+	    roberts = "roberts",
+	    scharr = "scharr",
+	    laplacian4 = "laplacian4",
+	    laplacian8 = "laplacian8",
+	    log = "log",
+	}
+	export enum EdgeOutput {
+	    magnitude = "magnitude",
+	    x = "x",
+	    y = "y",
+	    direction = "direction",
+	}
+	export enum FilterShape {
+	    ideal = "ideal",
+	    butterworth = "butterworth",
+	    gaussian = "gaussian",
+	}
+	export enum FrequencyChannels {
+	    luminance = "luminance",
+	    rgb = "rgb",
+	}
+	export enum FrequencyPass {
+	    lowpass = "lowpass",
+	    highpass = "highpass",
+	    bandpass = "bandpass",
+	    notch = "notch",
+	}
+	export enum GrayMethod {
+	    average = "average",
+	    rec601 = "rec601",
+	    rec709 = "rec709",
+	    rec2020 = "rec2020",
+	    lightness = "lightness",
+	    luminosity = "luminosity",
+	    channel = "channel",
+	    custom = "custom",
+	    decolorize = "decolorize",
+	}
 	export enum ImageFormat {
 	    jpg = "jpeg",
+	    png = "png",
 	    pbmP1 = "pbmP1",
 	    pbmP4 = "pbmP4",
 	    pgmP2 = "pgmP2",
@@ -9,6 +93,96 @@ export namespace main {
 	    ppmP3 = "ppmP3",
 	    ppmP6 = "ppmP6",
 	}
+	export enum MaskKind {
+	    rectangle = "rectangle",
+	    ellipse = "ellipse",
+	    polygon = "polygon",
+	    image = "image",
+	}
+	export enum MedianShape {
+	    square = "square",
+	    circle = "circle",
+	}
+	export enum NoiseKind {
+	    saltpepper = "saltpepper",
+	    gaussian = "gaussian",
+	    speckle = "speckle",
+	    poisson = "poisson",
+	    uniform = "uniform",
+	}
+	export enum ParamType {
+	    int = "int",
+	    float = "float",
+	    bool = "bool",
+	    enum = "enum",
+	    points = "points",
+	    text = "text",
+	}
+	export enum SharpenMethod {
+	    unsharp = "unsharp",
+	    highpass = "highpass",
+	}
+	export enum SizeFit {
+	    resize = "resize",
+	    topleft = "topleft",
+	    center = "center",
+	}
+	export enum SpectrumKind {
+	    magnitude = "magnitude",
+	    phase = "phase",
+	}
+	export enum ToneChannel {
+	    composite = "composite",
+	    red = "red",
+	    green = "green",
+	    blue = "blue",
+	}
+	export enum ToneTransform {
+	    brightness = "brightness",
+	    contrast = "contrast",
+	    gamma = "gamma",
+	    log = "log",
+	    exp = "exp",
+	    power = "power",
+	}
+	export class BlendOptions {
+	    mode: BlendMode;
+	    opacity: number;
+	    fit: SizeFit;
+	    offsetX: number;
+	    offsetY: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BlendOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.opacity = source["opacity"];
+	        this.fit = source["fit"];
+	        this.offsetX = source["offsetX"];
+	        this.offsetY = source["offsetY"];
+	    }
+	}
+	export class CannyOptions {
+	    sigma: number;
+	    low: number;
+	    high: number;
+	    thresholds: CannyThresholds;
+	
+	    static createFrom(source: any = {}) {
+	        return new CannyOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sigma = source["sigma"];
+	        this.low = source["low"];
+	        this.high = source["high"];
+	        this.thresholds = source["thresholds"];
+	    }
+	}
 	export class Cmyk {
 	    c: number;
 	    m: number;
@@ -27,6 +201,372 @@ export namespace main {
 	        this.k = source["k"];
 	    }
 	}
+	export class ConvolutionOptions {
+	    kernel: number[][];
+	    divisor: number;
+	    bias: number;
+	    border: BorderMode;
+	    constant: Rgb;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConvolutionOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kernel = source["kernel"];
+	        this.divisor = source["divisor"];
+	        this.bias = source["bias"];
+	        this.border = source["border"];
+	        this.constant = this.convertValues(source["constant"], Rgb);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CurvePoint {
+	    x: number;
+	    y: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CurvePoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.x = source["x"];
+	        this.y = source["y"];
+	    }
+	}
+	export class CurvesOptions {
+	    channel: ToneChannel;
+	    points: CurvePoint[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CurvesOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.channel = source["channel"];
+	        this.points = this.convertValues(source["points"], CurvePoint);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class EdgeOptions {
+	    operator: EdgeOperator;
+	    output: EdgeOutput;
+	    sigma: number;
+	    normalize: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new EdgeOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.operator = source["operator"];
+	        this.output = source["output"];
+	        this.sigma = source["sigma"];
+	        this.normalize = source["normalize"];
+	    }
+	}
+	export class Exif {
+	    make: string;
+	    model: string;
+	    orientation: number;
+	    exposureTime: string;
+	    fNumber: number;
+	    iso: number;
+	    focalLength: number;
+	    dateTime: string;
+	    hasGps: boolean;
+	    latitude: number;
+	    longitude: number;
+	    xResolution: number;
+	    yResolution: number;
+	    resolutionUnit: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Exif(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.make = source["make"];
+	        this.model = source["model"];
+	        this.orientation = source["orientation"];
+	        this.exposureTime = source["exposureTime"];
+	        this.fNumber = source["fNumber"];
+	        this.iso = source["iso"];
+	        this.focalLength = source["focalLength"];
+	        this.dateTime = source["dateTime"];
+	        this.hasGps = source["hasGps"];
+	        this.latitude = source["latitude"];
+	        this.longitude = source["longitude"];
+	        this.xResolution = source["xResolution"];
+	        this.yResolution = source["yResolution"];
+	        this.resolutionUnit = source["resolutionUnit"];
+	    }
+	}
+	export class FrequencyFilterOptions {
+	    pass: FrequencyPass;
+	    shape: FilterShape;
+	    cutoff: number;
+	    width: number;
+	    order: number;
+	    notches: NotchPoint[];
+	    channels: FrequencyChannels;
+	
+	    static createFrom(source: any = {}) {
+	        return new FrequencyFilterOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pass = source["pass"];
+	        this.shape = source["shape"];
+	        this.cutoff = source["cutoff"];
+	        this.width = source["width"];
+	        this.order = source["order"];
+	        this.notches = this.convertValues(source["notches"], NotchPoint);
+	        this.channels = source["channels"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GrayOptions {
+	    method: GrayMethod;
+	    channel: ToneChannel;
+	    weights: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GrayOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.method = source["method"];
+	        this.channel = source["channel"];
+	        this.weights = source["weights"];
+	    }
+	}
+	export class HueSaturationOptions {
+	    model: ColorModel;
+	    hue: number;
+	    saturation: number;
+	    lightness: number;
+	    colorize: boolean;
+	    restrict: boolean;
+	    rangeCenter: number;
+	    rangeWidth: number;
+	    rangeFeather: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HueSaturationOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.hue = source["hue"];
+	        this.saturation = source["saturation"];
+	        this.lightness = source["lightness"];
+	        this.colorize = source["colorize"];
+	        this.restrict = source["restrict"];
+	        this.rangeCenter = source["rangeCenter"];
+	        this.rangeWidth = source["rangeWidth"];
+	        this.rangeFeather = source["rangeFeather"];
+	    }
+	}
+	export class LevelsOptions {
+	    channel: ToneChannel;
+	    inputBlack: number;
+	    inputWhite: number;
+	    gamma: number;
+	    outputBlack: number;
+	    outputWhite: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LevelsOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.channel = source["channel"];
+	        this.inputBlack = source["inputBlack"];
+	        this.inputWhite = source["inputWhite"];
+	        this.gamma = source["gamma"];
+	        this.outputBlack = source["outputBlack"];
+	        this.outputWhite = source["outputWhite"];
+	    }
+	}
+	export class MaskPoint {
+	    x: number;
+	    y: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new MaskPoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.x = source["x"];
+	        this.y = source["y"];
+	    }
+	}
+	export class MetadataEntry {
+	    key: string;
+	    value: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MetadataEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.value = source["value"];
+	    }
+	}
+	export class NoiseOptions {
+	    kind: NoiseKind;
+	    amount: number;
+	    seed: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new NoiseOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.amount = source["amount"];
+	        this.seed = source["seed"];
+	    }
+	}
+	export class NotchPoint {
+	    u: number;
+	    v: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new NotchPoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.u = source["u"];
+	        this.v = source["v"];
+	    }
+	}
+	export class OperationInfo {
+	    name: string;
+	    label: string;
+	    category: string;
+	    params: ParamSpec[];
+	
+	    static createFrom(source: any = {}) {
+	        return new OperationInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.label = source["label"];
+	        this.category = source["category"];
+	        this.params = this.convertValues(source["params"], ParamSpec);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ParamSpec {
+	    name: string;
+	    label: string;
+	    type: ParamType;
+	    min: number;
+	    max: number;
+	    default: any;
+	    options: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ParamSpec(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.label = source["label"];
+	        this.type = source["type"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	        this.default = source["default"];
+	        this.options = source["options"];
+	    }
+	}
 	export class Rgb {
 	    r: number;
 	    g: number;
@@ -43,6 +583,124 @@ export namespace main {
 	        this.b = source["b"];
 	    }
 	}
+	export class SaveOptions {
+	    quality: number;
+	    metadata: MetadataEntry[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SaveOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.quality = source["quality"];
+	        this.metadata = this.convertValues(source["metadata"], MetadataEntry);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SelectionMask {
+	    kind: MaskKind;
+	    x: number;
+	    y: number;
+	    width: number;
+	    height: number;
+	    points: MaskPoint[];
+	    image: string;
+	    feather: number;
+	    invert: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SelectionMask(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.x = source["x"];
+	        this.y = source["y"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.points = this.convertValues(source["points"], MaskPoint);
+	        this.image = source["image"];
+	        this.feather = source["feather"];
+	        this.invert = source["invert"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SharpenOptions {
+	    method: SharpenMethod;
+	    amount: number;
+	    radius: number;
+	    threshold: number;
+	    luminance: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SharpenOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.method = source["method"];
+	        this.amount = source["amount"];
+	        this.radius = source["radius"];
+	        this.threshold = source["threshold"];
+	        this.luminance = source["luminance"];
+	    }
+	}
+	export class ToneOptions {
+	    transform: ToneTransform;
+	    amount: number;
+	    scale: number;
+	    pivot: ContrastPivot;
+	    luminance: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ToneOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.transform = source["transform"];
+	        this.amount = source["amount"];
+	        this.scale = source["scale"];
+	        this.pivot = source["pivot"];
+	        this.luminance = source["luminance"];
+	    }
+	}
 
 }
 
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGaussianBlurPremultiplied(t *testing.T) {
+	// left half opaque red, right half fully transparent black
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{255, 0, 0, 255})
+		}
+	}
+
+	blurred := gaussianBlur(img, 1.5, 0).(*image.NRGBA)
+	for x := 0; x < 8; x++ {
+		px := blurred.NRGBAAt(x, 2)
+		if px.A > 0 && px.R < 254 {
+			t.Errorf("pixel %d darkened by transparent neighbours: %v", x, px)
+		}
+	}
+	if a := blurred.NRGBAAt(4, 2).A; a == 0 || a == 255 {
+		t.Errorf("alpha should fade across the edge, got %d", a)
+	}
+}
+
+func TestGaussianKernel1D(t *testing.T) {
+	kernel := gaussianKernel1D(2, 0)
+	if len(kernel) != 13 {
+		t.Errorf("sigma 2 should derive radius 6, got length %d", len(kernel))
+	}
+	var sum float64
+	for _, v := range kernel {
+		sum += v
+	}
+	if sum < 0.9999 || sum > 1.0001 {
+		t.Errorf("kernel is not normalised, sum %f", sum)
+	}
+	if len(gaussianKernel1D(20, 60)) != 121 {
+		t.Error("explicit radius should be used")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+)
+
+type GrayMethod string
+
+const (
+	grayAverage    GrayMethod = "average"
+	grayRec601     GrayMethod = "rec601"
+	grayRec709     GrayMethod = "rec709"
+	grayRec2020    GrayMethod = "rec2020"
+	grayLightness  GrayMethod = "lightness"
+	grayLuminosity GrayMethod = "luminosity"
+	grayChannel    GrayMethod = "channel"
+	grayCustom     GrayMethod = "custom"
+	grayDecolorize GrayMethod = "decolorize"
+)
+
+var AllGrayMethods = []struct {
+	Value  GrayMethod
+	TSName string
+}{
+	{grayAverage, "average"},
+	{grayRec601, "rec601"},
+	{grayRec709, "rec709"},
+	{grayRec2020, "rec2020"},
+	{grayLightness, "lightness"},
+	{grayLuminosity, "luminosity"},
+	{grayChannel, "channel"},
+	{grayCustom, "custom"},
+	{grayDecolorize, "decolorize"},
+}
+
+// grayWeights are the red, green and blue weights of the methods that are a
+// fixed weighted sum of the gamma encoded channels.
+var grayWeights = map[GrayMethod][3]float64{
+	grayAverage: {1.0 / 3, 1.0 / 3, 1.0 / 3},
+	grayRec601:  {0.299, 0.587, 0.114},
+	grayRec709:  {0.2126, 0.7152, 0.0722},
+	grayRec2020: {0.2627, 0.6780, 0.0593},
+}
+
+// GrayOptions configures HandleGrayscale:
+//   - average, rec601, rec709 and rec2020: weighted sums of the channels
+//   - lightness: (max + min) / 2 of the channels
+//   - luminosity: Rec.709 luminance computed in linear light and encoded
+//     back to sRGB, which keeps the perceived brightness of saturated colours
+//   - channel: the red, green or blue channel named by Channel
+//   - custom: Weights, scaled so they sum to 1
+//   - decolorize: the weights that best keep the colour contrast between
+//     neighbouring and random pixel pairs as gray contrast
+type GrayOptions struct {
+	Method  GrayMethod  `json:"method"`
+	Channel ToneChannel `json:"channel"`
+	Weights [3]float64  `json:"weights"`
+}
+
+func (o GrayOptions) validate() error {
+	switch o.Method {
+	case grayAverage, grayRec601, grayRec709, grayRec2020, grayLightness, grayLuminosity, grayDecolorize:
+	case grayChannel:
+		if o.Channel != channelRed && o.Channel != channelGreen && o.Channel != channelBlue {
+			return fmt.Errorf("channel must be red, green or blue, got '%s'", o.Channel)
+		}
+	case grayCustom:
+		var sum float64
+		for _, w := range o.Weights {
+			if math.IsNaN(w) || math.IsInf(w, 0) || w < 0 {
+				return fmt.Errorf("weights must not be negative, got %v", o.Weights)
+			}
+			sum += w
+		}
+		if sum == 0 {
+			return fmt.Errorf("at least one weight must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown grayscale method '%s'", o.Method)
+	}
+	return nil
+}
+
+// weights returns the channel weights of the methods that are a weighted sum.
+// pix are the premultiplied pixels of the image, only decolorize needs them.
+func (o GrayOptions) weights(pix []uint32, w, h int) [3]float64 {
+	switch o.Method {
+	case grayChannel:
+		var weights [3]float64
+		weights[map[ToneChannel]int{channelRed: 0, channelGreen: 1, channelBlue: 2}[o.Channel]] = 1
+		return weights
+	case grayCustom:
+		sum := o.Weights[0] + o.Weights[1] + o.Weights[2]
+		return [3]float64{o.Weights[0] / sum, o.Weights[1] / sum, o.Weights[2] / sum}
+	case grayDecolorize:
+		return decolorizeWeights(pix, w, h)
+	}
+	return grayWeights[o.Method]
+}
+
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSrgb(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// lab converts sRGB channels in [0, 1] to CIE L*a*b* under D65.
+func lab(r, g, b float64) [3]float64 {
+	r, g, b = srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return t*24389/3132 + 4.0/29
+	}
+	fx := f((0.4124*r + 0.3576*g + 0.1805*b) / 0.95047)
+	fy := f(0.2126*r + 0.7152*g + 0.0722*b)
+	fz := f((0.0193*r + 0.1192*g + 0.9505*b) / 1.08883)
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// decolorSamples is the number of pixels per axis decolorizeWeights looks at.
+const decolorSamples = 64
+
+// decolorizeWeights picks the weights of the contrast preserving
+// decolourisation of Lu, Xu and Jia: among the weights in steps of 0.1 that
+// sum to 1, the one whose gray differences between pixel pairs best match
+// their colour differences in L*a*b*, whatever their sign.
+func decolorizeWeights(pix []uint32, w, h int) [3]float64 {
+	step := max(1, (max(w, h)+decolorSamples-1)/decolorSamples)
+	var colours [][3]float64
+	gw := (w + step - 1) / step
+	for y := 0; y < h; y += step {
+		for x := 0; x < w; x += step {
+			i := 4 * (y*w + x)
+			colours = append(colours, [3]float64{float64(pix[i]) / 65535, float64(pix[i+1]) / 65535, float64(pix[i+2]) / 65535})
+		}
+	}
+	// grid neighbours catch local contrast, random pairs the global one
+	var pairs [][2]int
+	for i := range colours {
+		if (i+1)%gw != 0 && i+1 < len(colours) {
+			pairs = append(pairs, [2]int{i, i + 1})
+		}
+		if i+gw < len(colours) {
+			pairs = append(pairs, [2]int{i, i + gw})
+		}
+	}
+	rng := rand.New(rand.NewSource(1))
+	for range colours {
+		pairs = append(pairs, [2]int{rng.Intn(len(colours)), rng.Intn(len(colours))})
+	}
+	deltas := make([]float64, len(pairs))
+	for k, p := range pairs {
+		c, d := colours[p[0]], colours[p[1]]
+		lc, ld := lab(c[0], c[1], c[2]), lab(d[0], d[1], d[2])
+		deltas[k] = math.Sqrt((lc[0]-ld[0])*(lc[0]-ld[0])+(lc[1]-ld[1])*(lc[1]-ld[1])+(lc[2]-ld[2])*(lc[2]-ld[2])) / 100
+	}
+	const sigma = 0.05
+	best, bestEnergy := grayWeights[grayRec601], math.Inf(-1)
+	for i := 0; i <= 10; i++ {
+		for j := 0; i+j <= 10; j++ {
+			weights := [3]float64{float64(i) / 10, float64(j) / 10, float64(10-i-j) / 10}
+			var energy float64
+			for k, p := range pairs {
+				c, d := colours[p[0]], colours[p[1]]
+				dg := weights[0]*(c[0]-d[0]) + weights[1]*(c[1]-d[1]) + weights[2]*(c[2]-d[2])
+				// log(exp(u) + exp(v)) without underflow
+				u := -(dg - deltas[k]) * (dg - deltas[k]) / (2 * sigma * sigma)
+				v := -(dg + deltas[k]) * (dg + deltas[k]) / (2 * sigma * sigma)
+				energy += max(u, v) + math.Log1p(math.Exp(-math.Abs(u-v)))
+			}
+			if energy > bestEnergy {
+				best, bestEnergy = weights, energy
+			}
+		}
+	}
+	return best
+}
+
+// grayscale converts the image to gray. Colours are taken premultiplied, so
+// transparent pixels turn black as if the image lay on black.
+func grayscale(img image.Image, opts GrayOptions) *image.Gray {
+	pix, w, h := premultiplied(img)
+	weights := opts.weights(pix, w, h)
+	result := image.NewGray(img.Bounds())
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < w; x++ {
+				i := 4 * (y*w + x)
+				r, g, b := float64(pix[i])/257, float64(pix[i+1])/257, float64(pix[i+2])/257
+				var v float64
+				switch opts.Method {
+				case grayLightness:
+					v = (max(r, g, b) + min(r, g, b)) / 2
+				case grayLuminosity:
+					v = 255 * linearToSrgb(0.2126*srgbToLinear(r/255)+0.7152*srgbToLinear(g/255)+0.0722*srgbToLinear(b/255))
+				default:
+					v = weights[0]*r + weights[1]*g + weights[2]*b
+				}
+				dst[x] = clampUint8(v)
+			}
+		}
+	})
+	return result
+}
+
+func (a *App) HandleGrayscale(base64str string, opts GrayOptions) string {
+	if err := opts.validate(); err != nil {
+		a.showInvalidParameters(err)
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return grayscale(m, opts)
+	})
+}
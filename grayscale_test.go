@@ -0,0 +1,91 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestGrayscaleMethods(t *testing.T) {
+	tests := []struct {
+		opts GrayOptions
+		in   color.NRGBA
+		want uint8
+	}{
+		// the old average divided every channel by 3 before adding and gave 0
+		{GrayOptions{Method: grayAverage}, color.NRGBA{2, 2, 2, 255}, 2},
+		{GrayOptions{Method: grayAverage}, color.NRGBA{255, 255, 255, 255}, 255},
+		{GrayOptions{Method: grayRec601}, color.NRGBA{0, 255, 0, 255}, 150},
+		{GrayOptions{Method: grayRec709}, color.NRGBA{0, 255, 0, 255}, 182},
+		{GrayOptions{Method: grayRec2020}, color.NRGBA{0, 0, 255, 255}, 15},
+		{GrayOptions{Method: grayLightness}, color.NRGBA{200, 100, 50, 255}, 125},
+		{GrayOptions{Method: grayLuminosity}, color.NRGBA{255, 0, 0, 255}, 127},
+		{GrayOptions{Method: grayLuminosity}, color.NRGBA{128, 128, 128, 255}, 128},
+		{GrayOptions{Method: grayChannel, Channel: channelGreen}, color.NRGBA{10, 20, 30, 255}, 20},
+		{GrayOptions{Method: grayCustom, Weights: [3]float64{2, 0, 2}}, color.NRGBA{10, 99, 30, 255}, 20},
+		{GrayOptions{Method: grayAverage}, color.NRGBA{200, 200, 200, 0}, 0},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.validate(); err != nil {
+			t.Fatalf("%+v: %v", tt.opts, err)
+		}
+		img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+		img.SetNRGBA(0, 0, tt.in)
+		if got := grayscale(img, tt.opts).GrayAt(0, 0).Y; got != tt.want {
+			t.Errorf("%s %v: got %d, want %d", tt.opts.Method, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecolorizeKeepsIsoluminantContrast(t *testing.T) {
+	// red and this gray have the same Rec.601 luma
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.NRGBA{76, 76, 76, 255}
+			if x < 20 {
+				c = color.NRGBA{255, 0, 0, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	rec601 := grayscale(img, GrayOptions{Method: grayRec601})
+	if d := int(rec601.GrayAt(0, 0).Y) - int(rec601.GrayAt(39, 0).Y); d < -1 || d > 1 {
+		t.Fatalf("test colours should be isoluminant, differ by %d", d)
+	}
+	gray := grayscale(img, GrayOptions{Method: grayDecolorize})
+	if d := math.Abs(float64(gray.GrayAt(0, 0).Y) - float64(gray.GrayAt(39, 0).Y)); d < 50 {
+		t.Errorf("decolorize should keep the contrast between red and gray, got %g", d)
+	}
+}
+
+func TestLegacyGrayMethods(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{200, 100, 50, 255})
+	a := NewApp()
+	want := grayscale(img, GrayOptions{Method: grayRec601}).GrayAt(0, 0)
+	ref := a.HandleToGrayPointWiseTransformations("weights", a.images.put(img))
+	m, err := a.imageFromRef(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := color.GrayModel.Convert(m.At(0, 0)); got != want {
+		t.Errorf("weights should be Rec.601, got %v, want %v", got, want)
+	}
+}
+
+func TestGrayOptionsRejects(t *testing.T) {
+	invalid := []GrayOptions{
+		{Method: "sepia"},
+		{Method: grayChannel, Channel: channelComposite},
+		{Method: grayCustom},
+		{Method: grayCustom, Weights: [3]float64{1, -1, 1}},
+		{Method: grayCustom, Weights: [3]float64{1, math.NaN(), 1}},
+	}
+	for _, opts := range invalid {
+		if err := opts.validate(); err == nil {
+			t.Errorf("%+v should be rejected", opts)
+		}
+	}
+}
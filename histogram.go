@@ -1,19 +1,16 @@
 package main
 
 import (
-	"bytes"
-	"encoding/base64"
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 func (a *App) HandleHistogram(base64str string) string {
 	var newM image.Image
-	m, err := decodeBasePngToImg(base64str, a.ctx)
+	m, err := a.imageFromRef(base64str)
 	if err != nil {
 		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
 			Type:          runtime.WarningDialog,
@@ -41,13 +38,7 @@ func (a *App) HandleHistogram(base64str string) string {
 		})
 		return ""
 	}
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newM); err != nil {
-		return ""
-	}
-	base64str = base64.StdEncoding.EncodeToString(buf.Bytes())
-	dataUrl := fmt.Sprintf("data:image/png;base64,%s", base64str)
-	return dataUrl
+	return a.refFromImage(base64str, newM)
 }
 
 func equalizeHistogram(img image.Image) image.Image {
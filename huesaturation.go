@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+type ColorModel string
+
+const (
+	modelHsv ColorModel = "hsv"
+	modelHsl ColorModel = "hsl"
+)
+
+var AllColorModels = []struct {
+	Value  ColorModel
+	TSName string
+}{
+	{modelHsv, "hsv"},
+	{modelHsl, "hsl"},
+}
+
+// maxSaturationScale is the largest factor saturation can be scaled by.
+const maxSaturationScale = 4
+
+// HueSaturationOptions configures HandleHueSaturation. In the chosen model
+// the hue is rotated by Hue degrees, the saturation scaled by Saturation and
+// the value or lightness moved towards white for positive Lightness and
+// towards black for negative, by that fraction of the way.
+//
+// With Colorize set every pixel is tinted instead: Hue is the hue in degrees
+// and Saturation the saturation all pixels get, only their value or
+// lightness is kept.
+//
+// With Restrict set only hues within RangeWidth / 2 degrees of RangeCenter
+// are adjusted fully, fading out over RangeFeather more degrees; grays have
+// no hue and are left as they are.
+type HueSaturationOptions struct {
+	Model        ColorModel `json:"model"`
+	Hue          float64    `json:"hue"`
+	Saturation   float64    `json:"saturation"`
+	Lightness    float64    `json:"lightness"`
+	Colorize     bool       `json:"colorize"`
+	Restrict     bool       `json:"restrict"`
+	RangeCenter  float64    `json:"rangeCenter"`
+	RangeWidth   float64    `json:"rangeWidth"`
+	RangeFeather float64    `json:"rangeFeather"`
+}
+
+func (o HueSaturationOptions) validate() error {
+	if o.Model != modelHsv && o.Model != modelHsl {
+		return fmt.Errorf("unknown colour model '%s'", o.Model)
+	}
+	hue, saturation := [2]float64{-180, 180}, [2]float64{0, maxSaturationScale}
+	if o.Colorize {
+		hue, saturation = [2]float64{0, 360}, [2]float64{0, 1}
+	}
+	if math.IsNaN(o.Hue) || o.Hue < hue[0] || o.Hue > hue[1] {
+		return fmt.Errorf("hue must be between %g and %g, got %g", hue[0], hue[1], o.Hue)
+	}
+	if math.IsNaN(o.Saturation) || o.Saturation < saturation[0] || o.Saturation > saturation[1] {
+		return fmt.Errorf("saturation must be between %g and %g, got %g", saturation[0], saturation[1], o.Saturation)
+	}
+	if math.IsNaN(o.Lightness) || o.Lightness < -1 || o.Lightness > 1 {
+		return fmt.Errorf("lightness must be between -1 and 1, got %g", o.Lightness)
+	}
+	if o.Restrict {
+		if math.IsNaN(o.RangeCenter) || o.RangeCenter < 0 || o.RangeCenter > 360 {
+			return fmt.Errorf("hue range centre must be between 0 and 360, got %g", o.RangeCenter)
+		}
+		if math.IsNaN(o.RangeWidth) || o.RangeWidth < 0 || o.RangeWidth > 360 {
+			return fmt.Errorf("hue range width must be between 0 and 360, got %g", o.RangeWidth)
+		}
+		if math.IsNaN(o.RangeFeather) || o.RangeFeather < 0 || o.RangeFeather > 180 {
+			return fmt.Errorf("hue range feather must be between 0 and 180, got %g", o.RangeFeather)
+		}
+	}
+	return nil
+}
+
+// hueWeight is how much of the adjustment a pixel of hue h gets.
+func (o HueSaturationOptions) hueWeight(h float64) float64 {
+	d := math.Abs(math.Mod(h-o.RangeCenter, 360))
+	d = min(d, 360-d) - o.RangeWidth/2
+	switch {
+	case d <= 0:
+		return 1
+	case d >= o.RangeFeather:
+		return 0
+	}
+	return 1 - d/o.RangeFeather
+}
+
+// adjust returns the adjusted colour of channels in [0, 1].
+func (o HueSaturationOptions) adjust(r, g, b float64) (float64, float64, float64) {
+	toModel, fromModel := rgbToHsv, hsvToRgb
+	if o.Model == modelHsl {
+		toModel, fromModel = rgbToHsl, hslToRgb
+	}
+	h, s, x := toModel(r, g, b)
+	weight := 1.0
+	if o.Restrict {
+		if s == 0 {
+			return r, g, b
+		}
+		if weight = o.hueWeight(h); weight == 0 {
+			return r, g, b
+		}
+	}
+	if o.Colorize {
+		h, s = o.Hue, o.Saturation
+	} else {
+		h, s = h+o.Hue, min(1, s*o.Saturation)
+	}
+	if o.Lightness > 0 {
+		x += o.Lightness * (1 - x)
+	} else {
+		x *= 1 + o.Lightness
+	}
+	nr, ng, nb := fromModel(h, s, x)
+	return r + weight*(nr-r), g + weight*(ng-g), b + weight*(nb-b)
+}
+
+func hueSaturation(img image.Image, opts HueSaturationOptions) image.Image {
+	src := toNRGBA(img)
+	b := img.Bounds()
+	result := image.NewNRGBA(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < b.Dx(); x++ {
+				p, out := row[4*x:4*x+4], dst[4*x:4*x+4]
+				r, g, bl := opts.adjust(float64(p[0])/255, float64(p[1])/255, float64(p[2])/255)
+				out[0], out[1], out[2], out[3] = clampUint8(255*r), clampUint8(255*g), clampUint8(255*bl), p[3]
+			}
+		}
+	})
+	return result
+}
+
+func (a *App) HandleHueSaturation(base64str string, opts HueSaturationOptions) string {
+	if err := opts.validate(); err != nil {
+		a.showInvalidParameters(err)
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return hueSaturation(m, opts)
+	})
+}
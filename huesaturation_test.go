@@ -0,0 +1,103 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestHsvHslRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 1000; i++ {
+		r, g, b := rng.Float64(), rng.Float64(), rng.Float64()
+		if i%10 == 0 {
+			g, b = r, r
+		}
+		h, s, v := rgbToHsv(r, g, b)
+		r1, g1, b1 := hsvToRgb(h, s, v)
+		h, s, l := rgbToHsl(r, g, b)
+		r2, g2, b2 := hslToRgb(h, s, l)
+		for _, d := range []float64{r1 - r, g1 - g, b1 - b, r2 - r, g2 - g, b2 - b} {
+			if math.Abs(d) > 1e-9 {
+				t.Fatalf("(%g, %g, %g) came back as (%g, %g, %g) and (%g, %g, %g)", r, g, b, r1, g1, b1, r2, g2, b2)
+			}
+		}
+	}
+	if h, s, l := rgbToHsl(1, 0.5, 0); h != 30 || s != 1 || l != 0.5 {
+		t.Errorf("orange should be hue 30, saturation 1, lightness 0.5, got %g %g %g", h, s, l)
+	}
+}
+
+func TestHueSaturationAdjustments(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 200}
+	tests := []struct {
+		name string
+		opts HueSaturationOptions
+		in   color.NRGBA
+		want color.NRGBA
+	}{
+		{"rotate hsv", HueSaturationOptions{Model: modelHsv, Hue: 120, Saturation: 1}, red, color.NRGBA{0, 255, 0, 200}},
+		{"rotate hsl backwards", HueSaturationOptions{Model: modelHsl, Hue: -120, Saturation: 1}, red, color.NRGBA{0, 0, 255, 200}},
+		{"desaturate hsv", HueSaturationOptions{Model: modelHsv, Saturation: 0}, red, color.NRGBA{255, 255, 255, 200}},
+		{"desaturate hsl", HueSaturationOptions{Model: modelHsl, Saturation: 0}, red, color.NRGBA{128, 128, 128, 200}},
+		{"saturate", HueSaturationOptions{Model: modelHsl, Saturation: 2}, color.NRGBA{191, 64, 64, 255}, color.NRGBA{255, 0, 0, 255}},
+		{"lighten", HueSaturationOptions{Model: modelHsl, Saturation: 1, Lightness: 1}, red, color.NRGBA{255, 255, 255, 200}},
+		{"darken half", HueSaturationOptions{Model: modelHsv, Saturation: 1, Lightness: -0.5}, red, color.NRGBA{128, 0, 0, 200}},
+		{"colorize gray", HueSaturationOptions{Model: modelHsl, Colorize: true, Hue: 240, Saturation: 1}, color.NRGBA{64, 64, 64, 255}, color.NRGBA{0, 0, 128, 255}},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.validate(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := hueSaturation(onePixel(tt.in), tt.opts).(*image.NRGBA).NRGBAAt(0, 0)
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHueRangeRestriction(t *testing.T) {
+	opts := HueSaturationOptions{Model: modelHsv, Saturation: 0, Restrict: true, RangeCenter: 350, RangeWidth: 40, RangeFeather: 20}
+	if err := opts.validate(); err != nil {
+		t.Fatal(err)
+	}
+	for h, want := range map[float64]float64{0: 1, 10: 1, 330: 1, 320: 0.5, 20: 0.5, 40: 0, 120: 0} {
+		if got := opts.hueWeight(h); math.Abs(got-want) > 1e-9 {
+			t.Errorf("weight of hue %g: got %g, want %g", h, got, want)
+		}
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{0, 255, 0, 255})
+	img.SetNRGBA(2, 0, color.NRGBA{90, 90, 90, 255})
+	got := hueSaturation(img, opts).(*image.NRGBA)
+	if c := got.NRGBAAt(0, 0); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("red is in the range and should lose its colour, got %v", c)
+	}
+	if c := got.NRGBAAt(1, 0); c != (color.NRGBA{0, 255, 0, 255}) {
+		t.Errorf("green is outside the range, got %v", c)
+	}
+	if c := got.NRGBAAt(2, 0); c != (color.NRGBA{90, 90, 90, 255}) {
+		t.Errorf("grays have no hue and should stay, got %v", c)
+	}
+}
+
+func TestHueSaturationOptionsRejects(t *testing.T) {
+	invalid := []HueSaturationOptions{
+		{Model: "lab", Saturation: 1},
+		{Model: modelHsv, Hue: 200, Saturation: 1},
+		{Model: modelHsv, Saturation: 5},
+		{Model: modelHsv, Saturation: 1, Lightness: 2},
+		{Model: modelHsl, Colorize: true, Hue: 100, Saturation: 2},
+		{Model: modelHsl, Colorize: true, Hue: -10, Saturation: 0.5},
+		{Model: modelHsv, Saturation: 1, Restrict: true, RangeWidth: 400},
+		{Model: modelHsv, Saturation: 1, Restrict: true, RangeWidth: 60, RangeFeather: math.NaN()},
+	}
+	for _, opts := range invalid {
+		if err := opts.validate(); err == nil {
+			t.Errorf("%+v should be rejected", opts)
+		}
+	}
+}
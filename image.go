@@ -6,9 +6,11 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"image"
 	"image/jpeg"
-	"io"
+	"image/png"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spakin/netpbm"
@@ -22,6 +24,7 @@ type (
 
 const (
 	jpg                   ImageFormat    = "jpeg"
+	pngFormat             ImageFormat    = "png"
 	pbmP1                 ImageFormat    = "pbmP1"
 	pbmP4                 ImageFormat    = "pbmP4"
 	pgmP2                 ImageFormat    = "pgmP2"
@@ -36,6 +39,7 @@ var AllImageFormats = []struct {
 	TSName string
 }{
 	{jpg, "jpg"},
+	{pngFormat, "png"},
 	{pbmP1, "pbmP1"},
 	{pbmP4, "pbmP4"},
 	{pgmP2, "pgmP2"},
@@ -44,15 +48,24 @@ var AllImageFormats = []struct {
 	{ppmP6, "ppmP6"},
 }
 
-func (format ImageFormat) validate(ctx context.Context) error {
+func (format ImageFormat) valid() bool {
 	switch format {
-	case jpg, pbmP1, pbmP4, pgmP2, pgmP5, ppmP3, ppmP6:
+	case jpg, pngFormat, pbmP1, pbmP4, pgmP2, pgmP5, ppmP3, ppmP6:
+		return true
+	default:
+		return false
+	}
+}
+
+func (format ImageFormat) validate(ctx context.Context) error {
+	switch {
+	case format.valid():
 		return nil
 	default:
 		runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
 			Type:          runtime.InfoDialog,
 			Title:         "Could not proceed with the operation",
-			Message:       fmt.Sprintf("'%s' is invalid file format, possible ones are jpeg, pbm, pgm, ppm", format),
+			Message:       fmt.Sprintf("'%s' is invalid file format, possible ones are jpeg, png, pbm, pgm, ppm", format),
 			DefaultButton: "Ok",
 		})
 		return errImageFormatUnknown
@@ -70,6 +83,9 @@ func (format ImageFormat) netpbm() bool {
 
 func (format ImageFormat) filters() (displayName string, pattern string) {
 	displayName, pattern = "JPEG Image", "*.jpg"
+	if format == pngFormat {
+		displayName, pattern = "PNG Image", "*.png"
+	}
 	if format.netpbm() {
 		displayName = strings.ToUpper(string(format[:3]))
 		pattern = fmt.Sprintf("*.%s", string(format[:3]))
@@ -118,53 +134,192 @@ func dataFromBase64(ctx context.Context, base64Image string) (string, error) {
 	return parts[1], nil
 }
 
+// SaveOptions tunes how an image is encoded when it is written to disk.
+type SaveOptions struct {
+	// JPEG quality between 1 and 100, 0 means the encoder default
+	Quality  int             `json:"quality"`
+	Metadata []MetadataEntry `json:"metadata"`
+}
+
+type SaveErrorCode string
+
+const (
+	saveErrInvalidFormat SaveErrorCode = "invalid_format"
+	saveErrInvalidPath   SaveErrorCode = "invalid_path"
+	saveErrImageNotFound SaveErrorCode = "image_not_found"
+	saveErrInvalidImage  SaveErrorCode = "invalid_image"
+	saveErrEncoding      SaveErrorCode = "encoding_failed"
+	saveErrWriting       SaveErrorCode = "write_failed"
+)
+
+// SaveError reaches the frontend as an object instead of a plain message, see
+// formatError.
+type SaveError struct {
+	Code    SaveErrorCode `json:"code"`
+	Path    string        `json:"path"`
+	Message string        `json:"message"`
+}
+
+func (e *SaveError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", e.Code, e.Message, e.Path)
+}
+
+// formatFromPath picks the format by extension, binary variants for Netpbm.
+func formatFromPath(path string) (ImageFormat, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return jpg, true
+	case ".png":
+		return pngFormat, true
+	case ".pbm":
+		return pbmP4, true
+	case ".pgm":
+		return pgmP5, true
+	case ".ppm", ".pnm":
+		return ppmP6, true
+	}
+	return "", false
+}
+
+func encodeImage(img image.Image, format ImageFormat, options SaveOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	switch {
+	case format == jpg:
+		var jpegOptions *jpeg.Options
+		if options.Quality > 0 {
+			jpegOptions = &jpeg.Options{Quality: min(options.Quality, 100)}
+		}
+		if err := jpeg.Encode(&buf, img, jpegOptions); err != nil {
+			return nil, err
+		}
+		return writeJpegMetadata(buf.Bytes(), options.Metadata)
+	case format == pngFormat:
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return writePngMetadata(buf.Bytes(), options.Metadata)
+	case format.netpbm():
+		if err := netpbm.Encode(&buf, img, &netpbm.EncodeOptions{
+			Format:   format.format(),
+			Plain:    format.plain(),
+			Comments: commentsFromMetadata(options.Metadata),
+		}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, errImageFormatUnknown
+}
+
+// rightImgBytes converts the canvas bytes to the chosen format and writes the
+// metadata entries in the way that format stores text. Bytes already in the
+// right format are kept as they are to avoid another lossy JPEG pass.
 func rightImgBytes(
 	imgBytes []byte,
 	format ImageFormat,
-	comments []string,
-	ctx context.Context,
+	metadata []MetadataEntry,
 ) ([]byte, error) {
-	if !format.netpbm() {
-		return imgBytes, nil
+	img, srcFormat, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return nil, err
 	}
+	switch {
+	case format == jpg && srcFormat == "jpeg":
+		return writeJpegMetadata(imgBytes, metadata)
+	case format == pngFormat && srcFormat == "png":
+		return writePngMetadata(imgBytes, metadata)
+	}
+	return encodeImage(img, format, SaveOptions{Metadata: metadata})
+}
 
-	img, err := jpeg.Decode(bytes.NewReader(imgBytes))
+// writeFileAtomic writes into a temporary file next to path and renames it,
+// so a failed save never leaves a truncated image behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var buf bytes.Buffer
-	if err := netpbm.Encode(&buf, img, &netpbm.EncodeOptions{
-		Format:   format.format(),
-		Plain:    format.plain(),
-		Comments: comments,
-	}); err != nil {
-		runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
-			Type:          runtime.InfoDialog,
-			Title:         "Encoding problem",
-			Message:       "Image could not be encoded",
-			DefaultButton: "Ok",
-		})
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
 	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// keep the permissions of a file that is replaced
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
 
-	imgBytes, err = io.ReadAll(&buf)
+// SaveImageTo writes the referenced image to path without any dialog. An empty
+// format is derived from the path extension. Failures are returned as
+// *SaveError.
+func (a *App) SaveImageTo(
+	imageRef string,
+	path string,
+	format ImageFormat,
+	options SaveOptions,
+) error {
+	if path == "" {
+		return &SaveError{Code: saveErrInvalidPath, Path: path, Message: "path is empty"}
+	}
+	if format == "" {
+		var ok bool
+		if format, ok = formatFromPath(path); !ok {
+			return &SaveError{
+				Code:    saveErrInvalidFormat,
+				Path:    path,
+				Message: fmt.Sprintf("cannot tell the format from extension '%s'", filepath.Ext(path)),
+			}
+		}
+	}
+	if !format.valid() {
+		return &SaveError{
+			Code:    saveErrInvalidFormat,
+			Path:    path,
+			Message: fmt.Sprintf("'%s' is invalid file format", format),
+		}
+	}
+
+	var (
+		m   image.Image
+		err error
+	)
+	if isImageRef(imageRef) {
+		if m, err = a.images.get(imageRef); err != nil {
+			return &SaveError{Code: saveErrImageNotFound, Path: path, Message: err.Error()}
+		}
+	} else if m, err = decodeDataURL(imageRef); err != nil {
+		return &SaveError{Code: saveErrInvalidImage, Path: path, Message: err.Error()}
+	}
+
+	imgBytes, err := encodeImage(m, format, options)
 	if err != nil {
-		runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
-			Type:          runtime.InfoDialog,
-			Title:         "Decoding problem",
-			Message:       "Image could not be decoded",
-			DefaultButton: "Ok",
-		})
-		return nil, errors.New("image could not be decoded")
+		return &SaveError{Code: saveErrEncoding, Path: path, Message: err.Error()}
+	}
+	if err := writeFileAtomic(path, imgBytes); err != nil {
+		return &SaveError{Code: saveErrWriting, Path: path, Message: err.Error()}
 	}
-	return imgBytes, nil
+	return nil
 }
 
 func (a *App) SaveCanvasImg(
 	base64Image string,
 	format ImageFormat,
-	comments []string,
+	metadata []MetadataEntry,
 ) {
-	fmt.Println(comments)
 	if err := format.validate(a.ctx); err != nil {
 		return
 	}
@@ -205,13 +360,22 @@ func (a *App) SaveCanvasImg(
 		})
 		return
 	}
+	if filepath == "" {
+		return
+	}
 
-	imgBytes, err = rightImgBytes(imgBytes, format, comments, a.ctx)
+	imgBytes, err = rightImgBytes(imgBytes, format, metadata)
 	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Encoding problem",
+			Message:       fmt.Sprintf("Image could not be encoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
 		return
 	}
 
-	if err := os.WriteFile(filepath, imgBytes, 0644); err != nil {
+	if err := writeFileAtomic(filepath, imgBytes); err != nil {
 		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
 			Type:          runtime.InfoDialog,
 			Title:         "File was not saved",
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveImageTo(t *testing.T) {
+	app := NewApp()
+	m := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	m.Set(1, 1, color.RGBA{255, 0, 0, 255})
+	ref := app.images.put(m)
+	dir := t.TempDir()
+
+	for _, name := range []string{"out.png", "out.jpg", "out.pgm", "out.ppm"} {
+		path := filepath.Join(dir, name)
+		err := app.SaveImageTo(ref, path, "", SaveOptions{
+			Quality:  90,
+			Metadata: []MetadataEntry{{Key: "Author", Value: "test"}},
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.Size() == 0 {
+			t.Errorf("%s was not written: %v", name, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestSaveImageToErrors(t *testing.T) {
+	app := NewApp()
+	ref := app.images.put(image.NewGray(image.Rect(0, 0, 1, 1)))
+	dir := t.TempDir()
+
+	tests := []struct {
+		ref, path string
+		format    ImageFormat
+		want      SaveErrorCode
+	}{
+		{ref, filepath.Join(dir, "out.bmp"), "", saveErrInvalidFormat},
+		{ref, filepath.Join(dir, "out.png"), "tiff", saveErrInvalidFormat},
+		{imageRefPrefix + "missing", filepath.Join(dir, "out.png"), "", saveErrImageNotFound},
+		{"data:image/png;base64,bm90IGEgcG5n", filepath.Join(dir, "out.png"), "", saveErrInvalidImage},
+		{ref, filepath.Join(dir, "missing", "out.png"), "", saveErrWriting},
+		{ref, "", pngFormat, saveErrInvalidPath},
+	}
+	for _, tt := range tests {
+		err := app.SaveImageTo(tt.ref, tt.path, tt.format, SaveOptions{})
+		var saveErr *SaveError
+		if !errors.As(err, &saveErr) {
+			t.Errorf("%s: expected *SaveError, got %v", tt.path, err)
+			continue
+		}
+		if saveErr.Code != tt.want {
+			t.Errorf("%s: got code %s, want %s", tt.path, saveErr.Code, tt.want)
+		}
+	}
+}
+
+func TestReleaseImage(t *testing.T) {
+	app := NewApp()
+	ref := app.images.put(image.NewGray(image.Rect(0, 0, 1, 1)))
+	app.ReleaseImage(ref)
+	if _, err := app.imageFromRef(ref); err == nil {
+		t.Error("a released image should be gone from the registry")
+	}
+	app.ReleaseImage(ref)
+}
+
+func TestWriteFileAtomicKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	existing, fresh := filepath.Join(dir, "existing.png"), filepath.Join(dir, "new.png")
+	if err := os.WriteFile(existing, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(existing, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]os.FileMode{existing: 0600, fresh: 0644} {
+		if err := writeFileAtomic(path, []byte("new")); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s: mode %v, want %v", filepath.Base(path), info.Mode().Perm(), want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type ToneChannel string
+
+const (
+	channelComposite ToneChannel = "composite"
+	channelRed       ToneChannel = "red"
+	channelGreen     ToneChannel = "green"
+	channelBlue      ToneChannel = "blue"
+)
+
+var AllToneChannels = []struct {
+	Value  ToneChannel
+	TSName string
+}{
+	{channelComposite, "composite"},
+	{channelRed, "red"},
+	{channelGreen, "green"},
+	{channelBlue, "blue"},
+}
+
+// luts returns the per channel tables that apply table to the channel, the
+// other channels are left as they are. Composite applies it to all three.
+func (c ToneChannel) luts(table [256]uint8) ([3][256]uint8, error) {
+	var identity [256]uint8
+	for v := range identity {
+		identity[v] = uint8(v)
+	}
+	tables := [3][256]uint8{identity, identity, identity}
+	switch c {
+	case channelComposite:
+		tables = [3][256]uint8{table, table, table}
+	case channelRed:
+		tables[0] = table
+	case channelGreen:
+		tables[1] = table
+	case channelBlue:
+		tables[2] = table
+	default:
+		return tables, fmt.Errorf("unknown channel '%s'", c)
+	}
+	return tables, nil
+}
+
+const (
+	minLevelsGamma = 0.1
+	maxLevelsGamma = 10.0
+)
+
+// LevelsOptions maps the input range [InputBlack, InputWhite] to the output
+// range [OutputBlack, OutputWhite], bending the midtones with Gamma, where
+// values above 1 brighten. Input levels outside the range are clipped. An
+// output black above the output white inverts the image.
+type LevelsOptions struct {
+	Channel     ToneChannel `json:"channel"`
+	InputBlack  float64     `json:"inputBlack"`
+	InputWhite  float64     `json:"inputWhite"`
+	Gamma       float64     `json:"gamma"`
+	OutputBlack float64     `json:"outputBlack"`
+	OutputWhite float64     `json:"outputWhite"`
+}
+
+func (o LevelsOptions) validate() error {
+	if _, err := o.Channel.luts([256]uint8{}); err != nil {
+		return err
+	}
+	for _, v := range []float64{o.InputBlack, o.InputWhite, o.OutputBlack, o.OutputWhite} {
+		if math.IsNaN(v) || v < 0 || v > 255 {
+			return fmt.Errorf("levels must be between 0 and 255, got %g", v)
+		}
+	}
+	if o.InputBlack >= o.InputWhite {
+		return fmt.Errorf("input black %g must be below input white %g", o.InputBlack, o.InputWhite)
+	}
+	if math.IsNaN(o.Gamma) || o.Gamma < minLevelsGamma || o.Gamma > maxLevelsGamma {
+		return fmt.Errorf("gamma must be between %g and %g, got %g", minLevelsGamma, maxLevelsGamma, o.Gamma)
+	}
+	return nil
+}
+
+func (o LevelsOptions) lut() [256]uint8 {
+	var table [256]uint8
+	for v := range table {
+		r := (min(max(float64(v), o.InputBlack), o.InputWhite) - o.InputBlack) / (o.InputWhite - o.InputBlack)
+		table[v] = clampUint8(o.OutputBlack + math.Pow(r, 1/o.Gamma)*(o.OutputWhite-o.OutputBlack))
+	}
+	return table
+}
+
+func levels(img image.Image, opts LevelsOptions) image.Image {
+	tables, _ := opts.Channel.luts(opts.lut())
+	return applyLuts(img, tables)
+}
+
+type CurvePoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// CurvesOptions maps every level through the curve through Points,
+// interpolated with a monotone cubic spline so the curve does not overshoot
+// between points. Levels left of the first or right of the last point keep
+// the value of that point.
+type CurvesOptions struct {
+	Channel ToneChannel  `json:"channel"`
+	Points  []CurvePoint `json:"points"`
+}
+
+func (o CurvesOptions) validate() error {
+	if _, err := o.Channel.luts([256]uint8{}); err != nil {
+		return err
+	}
+	return validateCurvePoints(o.Points)
+}
+
+// validateCurvePoints checks there are at least two points in [0, 255],
+// sorted by strictly increasing X.
+func validateCurvePoints(points []CurvePoint) error {
+	if len(points) < 2 {
+		return fmt.Errorf("curve needs at least 2 points, got %d", len(points))
+	}
+	for i, p := range points {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || p.X < 0 || p.X > 255 || p.Y < 0 || p.Y > 255 {
+			return fmt.Errorf("curve point (%g, %g) must lie between 0 and 255", p.X, p.Y)
+		}
+		if i > 0 && p.X <= points[i-1].X {
+			return errors.New("curve points must be sorted by increasing x")
+		}
+	}
+	return nil
+}
+
+// parseCurvePoints reads points written as "x,y x,y ...", the form curves
+// take in operation parameters.
+func parseCurvePoints(s string) ([]CurvePoint, error) {
+	var points []CurvePoint
+	for _, field := range strings.Fields(s) {
+		xs, ys, ok := strings.Cut(field, ",")
+		x, errX := strconv.ParseFloat(xs, 64)
+		y, errY := strconv.ParseFloat(ys, 64)
+		if !ok || errX != nil || errY != nil {
+			return nil, fmt.Errorf("curve point '%s' is not of the form x,y", field)
+		}
+		points = append(points, CurvePoint{x, y})
+	}
+	if err := validateCurvePoints(points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// monotoneSlopes returns the tangent at every point following Fritsch and
+// Carlson, which keeps the spline monotone wherever the points are.
+func monotoneSlopes(points []CurvePoint) []float64 {
+	n := len(points)
+	secants := make([]float64, n-1)
+	for i := range secants {
+		secants[i] = (points[i+1].Y - points[i].Y) / (points[i+1].X - points[i].X)
+	}
+	slopes := make([]float64, n)
+	slopes[0], slopes[n-1] = secants[0], secants[n-2]
+	for i := 1; i < n-1; i++ {
+		if secants[i-1]*secants[i] <= 0 {
+			continue
+		}
+		slopes[i] = (secants[i-1] + secants[i]) / 2
+	}
+	for i, d := range secants {
+		if d == 0 {
+			slopes[i], slopes[i+1] = 0, 0
+			continue
+		}
+		a, b := slopes[i]/d, slopes[i+1]/d
+		if h := math.Hypot(a, b); h > 3 {
+			slopes[i], slopes[i+1] = 3*a/h*d, 3*b/h*d
+		}
+	}
+	return slopes
+}
+
+func (o CurvesOptions) lut() [256]uint8 {
+	points := o.Points
+	slopes := monotoneSlopes(points)
+	var table [256]uint8
+	k := 0
+	for v := range table {
+		x := float64(v)
+		first, last := points[0], points[len(points)-1]
+		if x <= first.X {
+			table[v] = clampUint8(first.Y)
+			continue
+		}
+		if x >= last.X {
+			table[v] = clampUint8(last.Y)
+			continue
+		}
+		for x > points[k+1].X {
+			k++
+		}
+		p, q := points[k], points[k+1]
+		h := q.X - p.X
+		t := (x - p.X) / h
+		t2, t3 := t*t, t*t*t
+		y := (2*t3-3*t2+1)*p.Y + (t3-2*t2+t)*h*slopes[k] + (-2*t3+3*t2)*q.Y + (t3-t2)*h*slopes[k+1]
+		table[v] = clampUint8(y)
+	}
+	return table
+}
+
+func curves(img image.Image, opts CurvesOptions) image.Image {
+	tables, _ := opts.Channel.luts(opts.lut())
+	return applyLuts(img, tables)
+}
+
+func (a *App) HandleLevels(base64str string, opts LevelsOptions) string {
+	if err := opts.validate(); err != nil {
+		a.showInvalidParameters(err)
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return levels(m, opts)
+	})
+}
+
+func (a *App) HandleCurves(base64str string, opts CurvesOptions) string {
+	if err := opts.validate(); err != nil {
+		a.showInvalidParameters(err)
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return curves(m, opts)
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestLevelsLut(t *testing.T) {
+	tests := []struct {
+		opts LevelsOptions
+		want map[int]uint8
+	}{
+		{LevelsOptions{InputBlack: 0, InputWhite: 255, Gamma: 1, OutputBlack: 0, OutputWhite: 255}, map[int]uint8{0: 0, 77: 77, 255: 255}},
+		{LevelsOptions{InputBlack: 50, InputWhite: 150, Gamma: 1, OutputBlack: 0, OutputWhite: 255}, map[int]uint8{20: 0, 50: 0, 100: 128, 150: 255, 200: 255}},
+		{LevelsOptions{InputBlack: 0, InputWhite: 255, Gamma: 2, OutputBlack: 0, OutputWhite: 255}, map[int]uint8{64: 128}},
+		{LevelsOptions{InputBlack: 0, InputWhite: 255, Gamma: 1, OutputBlack: 100, OutputWhite: 200}, map[int]uint8{0: 100, 255: 200}},
+		{LevelsOptions{InputBlack: 0, InputWhite: 255, Gamma: 1, OutputBlack: 255, OutputWhite: 0}, map[int]uint8{0: 255, 255: 0, 55: 200}},
+	}
+	for _, tt := range tests {
+		tt.opts.Channel = channelComposite
+		if err := tt.opts.validate(); err != nil {
+			t.Fatalf("%+v: %v", tt.opts, err)
+		}
+		table := tt.opts.lut()
+		for in, want := range tt.want {
+			if table[in] != want {
+				t.Errorf("%+v: level %d maps to %d, want %d", tt.opts, in, table[in], want)
+			}
+		}
+	}
+}
+
+func TestCurvesSplineIsMonotone(t *testing.T) {
+	opts := CurvesOptions{Channel: channelComposite, Points: []CurvePoint{{0, 0}, {60, 10}, {70, 200}, {200, 210}, {255, 255}}}
+	table := opts.lut()
+	for _, p := range opts.Points {
+		if table[int(p.X)] != uint8(p.Y) {
+			t.Errorf("curve should pass through (%g, %g), got %d", p.X, p.Y, table[int(p.X)])
+		}
+	}
+	for v := 1; v < 256; v++ {
+		if table[v] < table[v-1] {
+			t.Fatalf("curve falls from %d to %d at level %d", table[v-1], table[v], v)
+		}
+	}
+	// a plain cubic spline would overshoot above 200 between 70 and 200
+	for v := 70; v <= 200; v++ {
+		if table[v] > 210 {
+			t.Fatalf("curve overshoots to %d at level %d", table[v], v)
+		}
+	}
+}
+
+func TestCurvesOutsidePoints(t *testing.T) {
+	table := CurvesOptions{Channel: channelComposite, Points: []CurvePoint{{50, 20}, {200, 240}}}.lut()
+	if table[0] != 20 || table[49] != 20 || table[230] != 240 {
+		t.Errorf("levels outside the points should keep the end values, got %d %d %d", table[0], table[49], table[230])
+	}
+	if table[125] != 130 {
+		t.Errorf("two points give a straight line, got %d at 125", table[125])
+	}
+}
+
+func TestCurvesSingleChannel(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{100, 100, 100, 77})
+	invert := []CurvePoint{{0, 255}, {255, 0}}
+	got := curves(img, CurvesOptions{Channel: channelGreen, Points: invert}).(*image.NRGBA).NRGBAAt(0, 0)
+	if got != (color.NRGBA{100, 155, 100, 77}) {
+		t.Errorf("only green should be inverted, got %v", got)
+	}
+}
+
+func TestCurvesRoundTripAsJSONAndParams(t *testing.T) {
+	opts := CurvesOptions{Channel: channelRed, Points: []CurvePoint{{0, 10}, {128, 140.5}, {255, 250}}}
+	data, err := json.Marshal(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back CurvesOptions
+	if err := json.Unmarshal(data, &back); err != nil || !reflect.DeepEqual(back, opts) {
+		t.Errorf("JSON round trip gave %+v, %v", back, err)
+	}
+	img := flatImage(90)
+	want := curves(img, opts)
+	got, err := applyOperation(img, "curves", map[string]any{"channel": "red", "points": "0,10 128,140.5 255,250"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("the curves operation should match CurvesOptions")
+	}
+}
+
+func TestLevelsAndCurvesReject(t *testing.T) {
+	invalidLevels := []LevelsOptions{
+		{Channel: "alpha", InputWhite: 255, Gamma: 1, OutputWhite: 255},
+		{Channel: channelRed, InputBlack: 200, InputWhite: 100, Gamma: 1, OutputWhite: 255},
+		{Channel: channelRed, InputWhite: 255, Gamma: 0, OutputWhite: 255},
+		{Channel: channelRed, InputWhite: 256, Gamma: 1, OutputWhite: 255},
+	}
+	for _, opts := range invalidLevels {
+		if err := opts.validate(); err == nil {
+			t.Errorf("%+v should be rejected", opts)
+		}
+	}
+	for _, points := range []string{"", "0,0", "0,0 x,1", "0,0 0,255", "100,0 50,255", "0,0 255,300", "0;0 255,255"} {
+		if _, err := parseCurvePoints(points); err == nil {
+			t.Errorf("points '%s' should be rejected", points)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"embed"
 
 	"github.com/wailsapp/wails/v2"
@@ -31,16 +32,42 @@ func main() {
 		MaxWidth:  0,
 		MaxHeight: 0,
 		AssetServer: &assetserver.Options{
-			Assets: assets,
+			Assets:  assets,
+			Handler: app.images,
 		},
 		BackgroundColour: &options.RGBA{R: 0, G: 0, B: 0, A: 0},
-		OnStartup:        app.startup,
+		OnStartup: func(ctx context.Context) {
+			app.startup(ctx)
+			worker.startup(ctx)
+		},
+		ErrorFormatter: formatError,
 		Bind: []any{
 			app,
 			worker,
 		},
 		EnumBind: []any{
 			AllImageFormats,
+			AllBorderModes,
+			AllMedianShapes,
+			AllEdgeOperators,
+			AllEdgeOutputs,
+			AllCannyThresholds,
+			AllSharpenMethods,
+			AllConductions,
+			AllFrequencyChannels,
+			AllSpectrumKinds,
+			AllFilterShapes,
+			AllFrequencyPasses,
+			AllParamTypes,
+			AllNoiseKinds,
+			AllMaskKinds,
+			AllToneTransforms,
+			AllContrastPivots,
+			AllToneChannels,
+			AllGrayMethods,
+			AllBlendModes,
+			AllSizeFits,
+			AllColorModels,
 		},
 		Windows: &windows.Options{
 			WindowIsTranslucent:  true,
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+type MaskKind string
+
+const (
+	maskRectangle MaskKind = "rectangle"
+	maskEllipse   MaskKind = "ellipse"
+	maskPolygon   MaskKind = "polygon"
+	maskImage     MaskKind = "image"
+)
+
+var AllMaskKinds = []struct {
+	Value  MaskKind
+	TSName string
+}{
+	{maskRectangle, "rectangle"},
+	{maskEllipse, "ellipse"},
+	{maskPolygon, "polygon"},
+	{maskImage, "image"},
+}
+
+// maxFeather is the largest feathering sigma in pixels.
+const maxFeather = 100
+
+// maskSamples is the number of sub-pixel samples per axis used to
+// anti-alias the edges of rectangles, ellipses and polygons.
+const maskSamples = 4
+
+type MaskPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// SelectionMask limits operations to a part of the image. Coordinates are
+// pixels from the top left corner of the image. Rectangles and ellipses are
+// given by their bounding box, whose width and height may be negative when
+// it was dragged up or left. An image mask is a reference or data URL whose
+// luminance is the weight of the operation; it is stretched to the size of
+// the image. Feather is the sigma of the blur that softens the mask edge, and
+// Invert selects everything outside the shape instead.
+type SelectionMask struct {
+	Kind    MaskKind    `json:"kind"`
+	X       float64     `json:"x"`
+	Y       float64     `json:"y"`
+	Width   float64     `json:"width"`
+	Height  float64     `json:"height"`
+	Points  []MaskPoint `json:"points"`
+	Image   string      `json:"image"`
+	Feather float64     `json:"feather"`
+	Invert  bool        `json:"invert"`
+}
+
+func (s SelectionMask) validate() error {
+	for _, v := range []float64{s.X, s.Y, s.Width, s.Height, s.Feather} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("mask coordinates must be finite numbers")
+		}
+	}
+	if s.Feather < 0 || s.Feather > maxFeather {
+		return fmt.Errorf("feather must be between 0 and %d, got %g", maxFeather, s.Feather)
+	}
+	switch s.Kind {
+	case maskRectangle, maskEllipse:
+		if s.Width == 0 || s.Height == 0 {
+			return fmt.Errorf("%s mask must not be empty", s.Kind)
+		}
+	case maskPolygon:
+		if len(s.Points) < 3 {
+			return fmt.Errorf("polygon mask needs at least 3 points, got %d", len(s.Points))
+		}
+	case maskImage:
+		if s.Image == "" {
+			return errors.New("image mask needs an image")
+		}
+	default:
+		return fmt.Errorf("unknown mask kind '%s'", s.Kind)
+	}
+	return nil
+}
+
+// inside reports whether a point lies in the rectangle, ellipse or polygon.
+func (s SelectionMask) inside(x, y float64) bool {
+	x0, x1 := min(s.X, s.X+s.Width), max(s.X, s.X+s.Width)
+	y0, y1 := min(s.Y, s.Y+s.Height), max(s.Y, s.Y+s.Height)
+	switch s.Kind {
+	case maskRectangle:
+		return x >= x0 && x < x1 && y >= y0 && y < y1
+	case maskEllipse:
+		dx := (x - (x0+x1)/2) / ((x1 - x0) / 2)
+		dy := (y - (y0+y1)/2) / ((y1 - y0) / 2)
+		return dx*dx+dy*dy <= 1
+	}
+	// even-odd rule, so self-intersecting polygons get holes like on canvas
+	in := false
+	for i, j := 0, len(s.Points)-1; i < len(s.Points); j, i = i, i+1 {
+		p, q := s.Points[i], s.Points[j]
+		if (p.Y > y) != (q.Y > y) && x < p.X+(y-p.Y)*(q.X-p.X)/(q.Y-p.Y) {
+			in = !in
+		}
+	}
+	return in
+}
+
+// weights returns the weight in [0, 1] of every pixel of a w x h image.
+// mask is the decoded mask image and is only used by image masks.
+func (s SelectionMask) weights(w, h int, mask image.Image) []float64 {
+	plane := make([]float64, w*h)
+	if s.Kind == maskImage {
+		gray := toGray(mask)
+		mb := gray.Bounds()
+		parallelRows(h, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				my := mb.Min.Y + y*mb.Dy()/h
+				for x := 0; x < w; x++ {
+					plane[y*w+x] = float64(gray.Pix[gray.PixOffset(mb.Min.X+x*mb.Dx()/w, my)]) / 255
+				}
+			}
+		})
+	} else {
+		parallelRows(h, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				for x := 0; x < w; x++ {
+					var hits int
+					for sy := 0; sy < maskSamples; sy++ {
+						for sx := 0; sx < maskSamples; sx++ {
+							if s.inside(float64(x)+(float64(sx)+0.5)/maskSamples, float64(y)+(float64(sy)+0.5)/maskSamples) {
+								hits++
+							}
+						}
+					}
+					plane[y*w+x] = float64(hits) / (maskSamples * maskSamples)
+				}
+			}
+		})
+	}
+	if s.Feather > 0 {
+		plane = blurPlane(plane, w, h, gaussianKernel1D(s.Feather, 0))
+	}
+	if s.Invert {
+		for i := range plane {
+			plane[i] = 1 - plane[i]
+		}
+	}
+	return plane
+}
+
+// blendInMask mixes result into src by the weights, in premultiplied space so
+// the alpha of both images is mixed too. Both images must have the same size.
+func blendInMask(src, result image.Image, weights []float64) *image.NRGBA {
+	before, w, h := premultiplied(src)
+	after, _, _ := premultiplied(result)
+	out := image.NewNRGBA(src.Bounds())
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dst := out.Pix[y*out.Stride:]
+			for x := 0; x < w; x++ {
+				i, m := y*w+x, weights[y*w+x]
+				var c [4]float64
+				for k := range c {
+					c[k] = float64(before[4*i+k]) + m*(float64(after[4*i+k])-float64(before[4*i+k]))
+				}
+				if c[3] <= 0 {
+					continue
+				}
+				for k := 0; k < 3; k++ {
+					dst[4*x+k] = clampUint8(c[k] * 255 / c[3])
+				}
+				dst[4*x+3] = clampUint8(c[3] / 257)
+			}
+		}
+	})
+	return out
+}
+
+// selection is the active mask of the app with its mask image decoded.
+type selection struct {
+	SelectionMask
+	image image.Image
+}
+
+// SetSelection limits every following operation to the mask until
+// ClearSelection is called: results are blended back into their source
+// inside the mask only. Operations that change the size of the image are
+// not affected.
+func (a *App) SetSelection(mask SelectionMask) error {
+	if err := mask.validate(); err != nil {
+		return err
+	}
+	s := &selection{SelectionMask: mask}
+	if mask.Kind == maskImage {
+		m, err := a.imageFromRef(mask.Image)
+		if err != nil {
+			return fmt.Errorf("mask image could not be decoded: %w", err)
+		}
+		s.image = m
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.selection = s
+	return nil
+}
+
+func (a *App) ClearSelection() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.selection = nil
+}
+
+// applySelection blends the result of an operation on the image behind ref
+// into that image inside the active selection. Without a selection, or when
+// the operation changed the size, the result is returned as it is.
+func (a *App) applySelection(ref string, result image.Image) image.Image {
+	a.mu.Lock()
+	s := a.selection
+	a.mu.Unlock()
+	if s == nil {
+		return result
+	}
+	src, err := a.imageFromRef(ref)
+	if err != nil || src.Bounds() != result.Bounds() {
+		return result
+	}
+	b := src.Bounds()
+	return blendInMask(src, result, s.weights(b.Dx(), b.Dy(), s.image))
+}
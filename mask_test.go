@@ -0,0 +1,122 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestMaskWeights(t *testing.T) {
+	rect := SelectionMask{Kind: maskRectangle, X: 2, Y: 3, Width: 4, Height: 5}
+	dragged := SelectionMask{Kind: maskRectangle, X: 6, Y: 8, Width: -4, Height: -5}
+	for _, mask := range []SelectionMask{rect, dragged} {
+		weights := mask.weights(10, 10, nil)
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				want := 0.0
+				if x >= 2 && x < 6 && y >= 3 && y < 8 {
+					want = 1
+				}
+				if weights[y*10+x] != want {
+					t.Fatalf("%+v: weight at %d,%d is %g, want %g", mask, x, y, weights[y*10+x], want)
+				}
+			}
+		}
+	}
+
+	ellipse := SelectionMask{Kind: maskEllipse, X: 0, Y: 0, Width: 10, Height: 10}.weights(10, 10, nil)
+	if ellipse[5*10+5] != 1 || ellipse[0] != 0 {
+		t.Errorf("ellipse: centre %g, corner %g", ellipse[5*10+5], ellipse[0])
+	}
+	if edge := ellipse[1*10+1]; edge <= 0 || edge >= 1 {
+		t.Errorf("ellipse edge should be anti-aliased, got %g", edge)
+	}
+
+	triangle := SelectionMask{Kind: maskPolygon, Points: []MaskPoint{{0, 0}, {10, 0}, {0, 10}}}.weights(10, 10, nil)
+	if triangle[1*10+1] != 1 || triangle[9*10+9] != 0 {
+		t.Errorf("triangle: inside %g, outside %g", triangle[1*10+1], triangle[9*10+9])
+	}
+
+	inverted := rect
+	inverted.Invert = true
+	if w := inverted.weights(10, 10, nil); w[0] != 1 || w[4*10+4] != 0 {
+		t.Errorf("inverted: outside %g, inside %g", w[0], w[4*10+4])
+	}
+
+	feathered := SelectionMask{Kind: maskRectangle, X: 0, Y: 0, Width: 10, Height: 20, Feather: 2}.weights(20, 20, nil)
+	if w := feathered[10*20+10]; w <= 0.1 || w >= 0.9 {
+		t.Errorf("feathered edge weight %g should be in between", w)
+	}
+	if feathered[10*20+2] < 0.99 || feathered[10*20+18] > 0.01 {
+		t.Errorf("feathering should fade out away from the edge: %g, %g", feathered[10*20+2], feathered[10*20+18])
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, 5, 5))
+	gray.SetGray(0, 0, color.Gray{255})
+	gray.SetGray(4, 4, color.Gray{51})
+	// the mask image is stretched to the size of the image
+	scaled := SelectionMask{Kind: maskImage, Image: "mask"}.weights(10, 10, gray)
+	if scaled[0] != 1 || scaled[1*10+1] != 1 || scaled[9*10+9] != 0.2 || scaled[5*10+5] != 0 {
+		t.Errorf("image mask weights %g %g %g %g", scaled[0], scaled[1*10+1], scaled[9*10+9], scaled[5*10+5])
+	}
+}
+
+func TestSelectionMaskValidate(t *testing.T) {
+	invalid := []SelectionMask{
+		{Kind: "lasso"},
+		{Kind: maskRectangle, Width: 0, Height: 4},
+		{Kind: maskEllipse, Width: 4, Height: 4, Feather: -1},
+		{Kind: maskPolygon, Points: []MaskPoint{{0, 0}, {1, 1}}},
+		{Kind: maskImage},
+	}
+	for _, mask := range invalid {
+		if mask.validate() == nil {
+			t.Errorf("%+v should be rejected", mask)
+		}
+	}
+}
+
+func TestSelectionLimitsOperations(t *testing.T) {
+	a := NewApp()
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+3] = 200, 255
+	}
+	ref := a.images.put(img)
+	if err := a.SetSelection(SelectionMask{Kind: maskRectangle, X: 0, Y: 0, Width: 4, Height: 8}); err != nil {
+		t.Fatal(err)
+	}
+
+	// threshold turns the red pixels black, but only in the left half
+	out, err := a.ApplyOperation(ref, "threshold", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := a.imageFromRef(out)
+	if c := color.NRGBAModel.Convert(m.At(1, 4)).(color.NRGBA); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("inside the selection got %v", c)
+	}
+	if c := color.NRGBAModel.Convert(m.At(6, 4)).(color.NRGBA); c != (color.NRGBA{200, 0, 0, 255}) {
+		t.Errorf("outside the selection got %v", c)
+	}
+
+	// operations that change the size cannot be blended
+	spectrum, err := a.ApplyOperation(a.images.put(image.NewGray(image.Rect(0, 0, 6, 6))), "spectrum", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := a.imageFromRef(spectrum); m.Bounds() != image.Rect(0, 0, 8, 8) {
+		t.Errorf("spectrum has bounds %v", m.Bounds())
+	}
+
+	a.ClearSelection()
+	out, _ = a.ApplyOperation(ref, "threshold", nil)
+	m, _ = a.imageFromRef(out)
+	if c := color.GrayModel.Convert(m.At(6, 4)).(color.Gray); c.Y != 0 {
+		t.Errorf("without selection the whole image should change, got %v", c)
+	}
+
+	if err := a.SetSelection(SelectionMask{Kind: maskImage, Image: "/images/missing"}); err == nil {
+		t.Error("a missing mask image should be rejected")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+type MedianShape string
+
+const (
+	medianSquare MedianShape = "square"
+	medianCircle MedianShape = "circle"
+)
+
+var AllMedianShapes = []struct {
+	Value  MedianShape
+	TSName string
+}{
+	{medianSquare, "square"},
+	{medianCircle, "circle"},
+}
+
+const (
+	minMedianWindow = 3
+	maxMedianWindow = 31
+)
+
+// medianHalfWidths returns, for every row offset of the window, how far the
+// window reaches left and right of the centre.
+func medianHalfWidths(size int, shape MedianShape) []int {
+	r := size / 2
+	halfWidths := make([]int, 2*r+1)
+	for dy := -r; dy <= r; dy++ {
+		if shape == medianCircle {
+			halfWidths[dy+r] = int(math.Sqrt(float64(r*r-dy*dy)) + 0.5)
+		} else {
+			halfWidths[dy+r] = r
+		}
+	}
+	return halfWidths
+}
+
+// medianPlane filters one 8-bit channel with Huang's sliding histogram. Moving
+// the window one pixel right only removes the left column and adds the right
+// one, and the median is walked from its previous position instead of
+// rescanning the histogram, which keeps large windows fast. Edges are clamped.
+// Pixels whose alpha is 0 are left out of the window, a nil alpha counts
+// every pixel. A window without any counted pixel keeps the source value.
+func medianPlane(plane, alpha []uint8, w, h int, halfWidths []int) []uint8 {
+	out := make([]uint8, len(plane))
+	// every row starts its own histogram, so rows can run in parallel
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			medianRow(plane, alpha, out, y, w, h, halfWidths)
+		}
+	})
+	return out
+}
+
+func medianRow(plane, alpha, out []uint8, y, w, h int, halfWidths []int) {
+	r := len(halfWidths) / 2
+	clampX := func(x int) int { return min(max(x, 0), w-1) }
+	clampY := func(y int) int { return min(max(y, 0), h-1) }
+	counted := func(i int) bool { return alpha == nil || alpha[i] != 0 }
+
+	var hist [256]int
+	count := 0
+	for dy := -r; dy <= r; dy++ {
+		row := clampY(y+dy) * w
+		hw := halfWidths[dy+r]
+		for dx := -hw; dx <= hw; dx++ {
+			if i := row + clampX(dx); counted(i) {
+				hist[plane[i]]++
+				count++
+			}
+		}
+	}
+	// median is the smallest value m with more than count/2 pixels <= m,
+	// lt counts the pixels strictly below m
+	m, lt := 0, 0
+	for x := 0; x < w; x++ {
+		if x > 0 {
+			for dy := -r; dy <= r; dy++ {
+				row := clampY(y+dy) * w
+				hw := halfWidths[dy+r]
+				if i := row + clampX(x-1-hw); counted(i) {
+					old := int(plane[i])
+					hist[old]--
+					count--
+					if old < m {
+						lt--
+					}
+				}
+				if i := row + clampX(x+hw); counted(i) {
+					added := int(plane[i])
+					hist[added]++
+					count++
+					if added < m {
+						lt++
+					}
+				}
+			}
+		}
+		if count == 0 {
+			out[y*w+x] = plane[y*w+x]
+			continue
+		}
+		half := count / 2
+		for lt > half {
+			m--
+			lt -= hist[m]
+		}
+		for lt+hist[m] <= half {
+			lt += hist[m]
+			m++
+		}
+		out[y*w+x] = uint8(m)
+	}
+}
+
+// medianFilter takes the median of each colour channel over the window and
+// keeps the alpha channel of the source. Transparent pixels are ignored so
+// their colour does not creep into the edges of visible ones.
+func medianFilter(img image.Image, size int, shape MedianShape) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := toNRGBA(img)
+	planes := [4][]uint8{make([]uint8, w*h), make([]uint8, w*h), make([]uint8, w*h), make([]uint8, w*h)}
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				i := y*w + x
+				planes[0][i], planes[1][i], planes[2][i], planes[3][i] = row[4*x], row[4*x+1], row[4*x+2], row[4*x+3]
+			}
+		}
+	})
+
+	halfWidths := medianHalfWidths(size, shape)
+	r := medianPlane(planes[0], planes[3], w, h, halfWidths)
+	g := medianPlane(planes[1], planes[3], w, h, halfWidths)
+	bl := medianPlane(planes[2], planes[3], w, h, halfWidths)
+
+	result := image.NewNRGBA(b)
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := result.Pix[y*result.Stride:]
+			for x := 0; x < w; x++ {
+				i := y*w + x
+				row[4*x], row[4*x+1], row[4*x+2], row[4*x+3] = r[i], g[i], bl[i], planes[3][i]
+			}
+		}
+	})
+	return result
+}
+
+func (a *App) HandleMedianFilter(base64str string, size int, shape MedianShape) string {
+	if size%2 == 0 || size < minMedianWindow || size > maxMedianWindow ||
+		(shape != medianSquare && shape != medianCircle) {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:  runtime.InfoDialog,
+			Title: "Invalid parameters",
+			Message: fmt.Sprintf(
+				"Window size must be odd and between %d and %d, shape square or circle",
+				minMedianWindow, maxMedianWindow,
+			),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	return a.refFromImage(base64str, medianFilter(m, size, shape))
+}
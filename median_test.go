@@ -0,0 +1,63 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// bruteMedian sorts the window of one channel, the reference for medianPlane.
+func bruteMedian(plane []uint8, w, h, x, y int, halfWidths []int) uint8 {
+	r := len(halfWidths) / 2
+	var values []uint8
+	for dy := -r; dy <= r; dy++ {
+		sy := min(max(y+dy, 0), h-1)
+		for dx := -halfWidths[dy+r]; dx <= halfWidths[dy+r]; dx++ {
+			sx := min(max(x+dx, 0), w-1)
+			values = append(values, plane[sy*w+sx])
+		}
+	}
+	slices.Sort(values)
+	return values[len(values)/2]
+}
+
+func TestMedianPlaneMatchesSorting(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	w, h := 23, 17
+	plane := make([]uint8, w*h)
+	for i := range plane {
+		plane[i] = uint8(rng.Intn(256))
+	}
+	for _, shape := range []MedianShape{medianSquare, medianCircle} {
+		for _, size := range []int{3, 5, 9, 31} {
+			halfWidths := medianHalfWidths(size, shape)
+			out := medianPlane(plane, nil, w, h, halfWidths)
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					if want := bruteMedian(plane, w, h, x, y, halfWidths); out[y*w+x] != want {
+						t.Fatalf("%s %d: pixel (%d,%d) = %d, want %d", shape, size, x, y, out[y*w+x], want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMedianFilterKeepsAlpha(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 5, 5))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+	img.SetNRGBA(2, 2, color.NRGBA{10, 20, 30, 0})
+	out := medianFilter(img, 3, medianSquare)
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			_, _, _, want := img.At(x, y).RGBA()
+			if _, _, _, got := out.At(x, y).RGBA(); got != want {
+				t.Fatalf("alpha at (%d,%d) = %d, want %d", x, y, got, want)
+			}
+		}
+	}
+}
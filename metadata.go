@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MetadataEntry is a single textual key/value pair carried with an image. It
+// maps to a PNG tEXt/iTXt chunk, a JPEG COM segment or a Netpbm comment line.
+// Formats without keys store non-comment entries as "Key: value".
+type MetadataEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+const metadataCommentKey = "Comment"
+
+// pngTextKeywordMax is the longest keyword allowed in tEXt/iTXt chunks, in
+// Latin-1 bytes
+const pngTextKeywordMax = 79
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// metadataFromComments turns keyless comment lines (Netpbm, JPEG COM) into
+// entries, splitting "Key: value" lines written by commentsFromMetadata.
+func metadataFromComments(comments []string) []MetadataEntry {
+	var entries []MetadataEntry
+	for _, c := range comments {
+		c = strings.TrimPrefix(c, "#")
+		c = strings.TrimPrefix(c, " ")
+		if key, value, ok := strings.Cut(c, ": "); ok && metadataKeyValid(key) {
+			entries = append(entries, MetadataEntry{Key: key, Value: value})
+			continue
+		}
+		entries = append(entries, MetadataEntry{Key: metadataCommentKey, Value: c})
+	}
+	return entries
+}
+
+// commentFromMetadata is the keyless form of an entry, "Key: value" unless
+// it is a plain comment.
+func commentFromMetadata(e MetadataEntry) string {
+	if e.Key == "" || e.Key == metadataCommentKey {
+		return e.Value
+	}
+	return fmt.Sprintf("%s: %s", e.Key, e.Value)
+}
+
+// truncateUtf8 cuts s to at most n bytes without splitting a character.
+func truncateUtf8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// commentsFromMetadata returns Netpbm comment lines, which cannot span lines,
+// so multi-line values are split.
+func commentsFromMetadata(entries []MetadataEntry) []string {
+	var comments []string
+	for _, e := range entries {
+		for _, line := range strings.Split(e.Value, "\n") {
+			comments = append(comments, commentFromMetadata(MetadataEntry{Key: e.Key, Value: line}))
+		}
+	}
+	return comments
+}
+
+// metadataKeyValid reports whether key is usable as a single-word key, which
+// keeps ordinary comments containing ": " from being split on load.
+func metadataKeyValid(key string) bool {
+	if key == "" || len(key) > pngTextKeywordMax {
+		return false
+	}
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func latin1(s string) bool {
+	for _, r := range s {
+		if r > 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+func readPngMetadata(data []byte) ([]MetadataEntry, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("not a png stream")
+	}
+	var entries []MetadataEntry
+	pos := len(pngSignature)
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		if pos+12+length > len(data) {
+			return entries, fmt.Errorf("chunk %s overflows the file", typ)
+		}
+		chunk := data[pos+8 : pos+8+length]
+		pos += 12 + length
+
+		switch typ {
+		case "tEXt":
+			key, value, _ := bytes.Cut(chunk, []byte{0})
+			entries = append(entries, MetadataEntry{Key: fromLatin1(key), Value: fromLatin1(value)})
+		case "zTXt":
+			key, rest, _ := bytes.Cut(chunk, []byte{0})
+			if len(rest) < 1 {
+				continue
+			}
+			value, err := inflate(rest[1:])
+			if err != nil {
+				return entries, fmt.Errorf("zTXt %s: %v", key, err)
+			}
+			entries = append(entries, MetadataEntry{Key: fromLatin1(key), Value: fromLatin1(value)})
+		case "iTXt":
+			key, rest, _ := bytes.Cut(chunk, []byte{0})
+			if len(rest) < 2 {
+				continue
+			}
+			compressed := rest[0] == 1
+			_, rest, _ = bytes.Cut(rest[2:], []byte{0}) // language tag
+			_, value, _ := bytes.Cut(rest, []byte{0})   // translated keyword
+			if compressed {
+				var err error
+				if value, err = inflate(value); err != nil {
+					return entries, fmt.Errorf("iTXt %s: %v", key, err)
+				}
+			}
+			entries = append(entries, MetadataEntry{Key: fromLatin1(key), Value: string(value)})
+		case "IEND":
+			return entries, nil
+		}
+	}
+	return entries, nil
+}
+
+func fromLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func inflate(b []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, typ...)
+	chunk = append(chunk, data...)
+	crc := crc32.NewIEEE()
+	crc.Write(chunk[4:])
+	return binary.BigEndian.AppendUint32(chunk, crc.Sum32())
+}
+
+// writePngMetadata inserts text chunks right after IHDR. Latin-1 values go to
+// tEXt, anything else to an uncompressed iTXt chunk.
+func writePngMetadata(data []byte, entries []MetadataEntry) ([]byte, error) {
+	if len(entries) == 0 {
+		return data, nil
+	}
+	if !bytes.HasPrefix(data, pngSignature) || len(data) < len(pngSignature)+8 {
+		return nil, errors.New("not a png stream")
+	}
+	ihdrEnd := len(pngSignature) + 12 + int(binary.BigEndian.Uint32(data[len(pngSignature):]))
+	if ihdrEnd > len(data) {
+		return nil, errors.New("truncated IHDR chunk")
+	}
+
+	var chunks []byte
+	for _, e := range entries {
+		key := e.Key
+		if key == "" {
+			key = metadataCommentKey
+		}
+		// keywords are Latin-1 in every text chunk, only iTXt values may
+		// be other text
+		if !latin1(key) {
+			return nil, fmt.Errorf("metadata key %q must only use Latin-1 characters", key)
+		}
+		keyword := toLatin1(key)
+		if len(keyword) > pngTextKeywordMax {
+			keyword = keyword[:pngTextKeywordMax]
+		}
+		if latin1(e.Value) {
+			text := append(keyword, 0)
+			text = append(text, toLatin1(e.Value)...)
+			chunks = append(chunks, pngChunk("tEXt", text)...)
+			continue
+		}
+		if !utf8.ValidString(e.Value) {
+			return nil, fmt.Errorf("metadata %q is not valid utf-8", key)
+		}
+		// keyword, null, compression flag, method, empty language, empty translation
+		text := append(keyword, 0, 0, 0, 0, 0)
+		text = append(text, e.Value...)
+		chunks = append(chunks, pngChunk("iTXt", text)...)
+	}
+
+	out := make([]byte, 0, len(data)+len(chunks))
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunks...)
+	return append(out, data[ihdrEnd:]...), nil
+}
+
+func toLatin1(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+func readJpegComments(data []byte) ([]string, error) {
+	var comments []string
+	err := walkJpegSegments(data, func(marker byte, _ int, payload []byte) bool {
+		if marker == 0xfe {
+			comments = append(comments, string(payload))
+		}
+		return true
+	})
+	return comments, err
+}
+
+// writeJpegMetadata inserts one COM segment per entry after the leading APPn
+// segments, JFIF requires APP0 to follow SOI directly.
+func writeJpegMetadata(data []byte, entries []MetadataEntry) ([]byte, error) {
+	if len(entries) == 0 {
+		return data, nil
+	}
+	insertAt := 2
+	err := walkJpegSegments(data, func(marker byte, offset int, payload []byte) bool {
+		if marker < 0xe0 || marker > 0xef {
+			return false
+		}
+		insertAt = offset + 4 + len(payload)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []byte
+	for _, e := range entries {
+		c := commentFromMetadata(e)
+		// segment length is 16 bits and includes itself
+		c = truncateUtf8(c, 0xffff-2)
+		segments = append(segments, 0xff, 0xfe)
+		segments = binary.BigEndian.AppendUint16(segments, uint16(len(c)+2))
+		segments = append(segments, c...)
+	}
+
+	out := make([]byte, 0, len(data)+len(segments))
+	out = append(out, data[:insertAt]...)
+	out = append(out, segments...)
+	return append(out, data[insertAt:]...), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var testMetadata = []MetadataEntry{
+	{Key: "Comment", Value: "plain comment"},
+	{Key: "Author", Value: "Damian"},
+	{Key: "Title", Value: "Zażółć gęślą jaźń"},
+}
+
+func TestPngMetadataRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	data, err := writePngMetadata(buf.Bytes(), testMetadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("png with text chunks does not decode: %v", err)
+	}
+	got, err := readPngMetadata(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, testMetadata) {
+		t.Errorf("got %v, want %v", got, testMetadata)
+	}
+}
+
+func TestPngMetadataKeywords(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	// 60 Latin-1 characters are 120 bytes of utf-8 and must not be cut
+	fits := strings.Repeat("é", 60)
+	long := strings.Repeat("é", 100)
+	data, err := writePngMetadata(buf.Bytes(), []MetadataEntry{{Key: fits, Value: "a"}, {Key: long, Value: "ü ∑"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readPngMetadata(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MetadataEntry{{Key: fits, Value: "a"}, {Key: strings.Repeat("é", pngTextKeywordMax), Value: "ü ∑"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := writePngMetadata(buf.Bytes(), []MetadataEntry{{Key: "日本", Value: "a"}}); err == nil {
+		t.Error("keywords outside Latin-1 should be rejected")
+	}
+}
+
+func TestJpegMetadataRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := writeJpegMetadata(buf.Bytes(), testMetadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("jpeg with COM segments does not decode: %v", err)
+	}
+	comments, err := readJpegComments(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := metadataFromComments(comments); !reflect.DeepEqual(got, testMetadata) {
+		t.Errorf("got %v, want %v", got, testMetadata)
+	}
+}
+
+func TestJpegLongComment(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// 3-byte characters do not end at the 65533 byte limit
+	long := strings.Repeat("€", 30000)
+	data, err := writeJpegMetadata(buf.Bytes(), []MetadataEntry{{Value: long}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments, err := readJpegComments(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || !utf8.ValidString(comments[0]) || !strings.HasPrefix(long, comments[0]) || len(comments[0]) != 65532 {
+		t.Errorf("comment was not cut on a character boundary, got %d bytes", len(comments[0]))
+	}
+}
+
+func TestMetadataFromNetpbmComments(t *testing.T) {
+	comments := commentsFromMetadata([]MetadataEntry{
+		{Key: "Comment", Value: "two\nlines"},
+		{Key: "Author", Value: "Damian"},
+	})
+	want := []string{"two", "lines", "Author: Damian"}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("got %q, want %q", comments, want)
+	}
+
+	got := metadataFromComments([]string{"# created by GIMP", "# Source: scanner", "# two words: stay"})
+	want2 := []MetadataEntry{
+		{Key: metadataCommentKey, Value: "created by GIMP"},
+		{Key: "Source", Value: "scanner"},
+		{Key: metadataCommentKey, Value: "two words: stay"},
+	}
+	if !reflect.DeepEqual(got, want2) {
+		t.Errorf("got %v, want %v", got, want2)
+	}
+}
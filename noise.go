@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+type NoiseKind string
+
+const (
+	noiseSaltPepper NoiseKind = "saltpepper"
+	noiseGaussian   NoiseKind = "gaussian"
+	noiseSpeckle    NoiseKind = "speckle"
+	noisePoisson    NoiseKind = "poisson"
+	noiseUniform    NoiseKind = "uniform"
+)
+
+var AllNoiseKinds = []struct {
+	Value  NoiseKind
+	TSName string
+}{
+	{noiseSaltPepper, "saltpepper"},
+	{noiseGaussian, "gaussian"},
+	{noiseSpeckle, "speckle"},
+	{noisePoisson, "poisson"},
+	{noiseUniform, "uniform"},
+}
+
+// noiseLimits is the largest useful Amount of every kind.
+var noiseLimits = map[NoiseKind]float64{
+	noiseSaltPepper: 1,
+	noiseGaussian:   255,
+	noiseSpeckle:    4,
+	noisePoisson:    100,
+	noiseUniform:    255,
+}
+
+// NoiseOptions configures HandleNoise. Amount depends on the kind:
+//   - salt and pepper: density, the fraction of pixels set to black or white
+//   - gaussian: standard deviation added to every channel, 0-255 scale
+//   - speckle: standard deviation of the factor n in v * (1 + n)
+//   - poisson: photons per gray level, lower values are noisier
+//   - uniform: half width of the interval the added value is drawn from
+//
+// The same seed always gives the same noise for an image of the same size.
+type NoiseOptions struct {
+	Kind   NoiseKind `json:"kind"`
+	Amount float64   `json:"amount"`
+	Seed   int64     `json:"seed"`
+}
+
+func (o NoiseOptions) validate() error {
+	limit, ok := noiseLimits[o.Kind]
+	if !ok {
+		return fmt.Errorf("unknown noise kind '%s'", o.Kind)
+	}
+	if math.IsNaN(o.Amount) || o.Amount < 0 || o.Amount > limit {
+		return fmt.Errorf("%s amount must be between 0 and %g, got %g", o.Kind, limit, o.Amount)
+	}
+	if o.Kind == noisePoisson && o.Amount == 0 {
+		return fmt.Errorf("poisson amount must be positive")
+	}
+	return nil
+}
+
+// rowRand returns the random source of one row. Every row has its own stream
+// derived from the seed, so the result does not depend on how the rows are
+// spread over the workers.
+func rowRand(seed int64, y int) *rand.Rand {
+	// splitmix64 finaliser, neighbouring rows get unrelated seeds
+	z := uint64(seed) + uint64(y+1)*0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return rand.New(rand.NewSource(int64(z ^ z>>31)))
+}
+
+// poisson draws from a Poisson distribution. Knuth's method is exact but
+// slow for large means, where the normal approximation is good enough.
+func poisson(rng *rand.Rand, lambda float64) float64 {
+	if lambda <= 0 {
+		return 0
+	}
+	if lambda > 30 {
+		return max(0, math.Round(lambda+math.Sqrt(lambda)*rng.NormFloat64()))
+	}
+	limit := math.Exp(-lambda)
+	k, p := 0.0, rng.Float64()
+	for p > limit {
+		k++
+		p *= rng.Float64()
+	}
+	return k
+}
+
+// addNoise degrades the colour channels of every pixel independently, alpha
+// is kept. Salt and pepper sets the whole pixel to black or white.
+func addNoise(img image.Image, opts NoiseOptions) image.Image {
+	src := toNRGBA(img)
+	b := img.Bounds()
+	w := b.Dx()
+	result := image.NewNRGBA(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			rng := rowRand(opts.Seed, y)
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < w; x++ {
+				px, out := row[4*x:4*x+4], dst[4*x:4*x+4]
+				copy(out, px)
+				if opts.Kind == noiseSaltPepper {
+					if rng.Float64() < opts.Amount {
+						v := uint8(0)
+						if rng.Intn(2) == 1 {
+							v = 255
+						}
+						out[0], out[1], out[2] = v, v, v
+					}
+					continue
+				}
+				for c := 0; c < 3; c++ {
+					v := float64(px[c])
+					switch opts.Kind {
+					case noiseGaussian:
+						v += opts.Amount * rng.NormFloat64()
+					case noiseSpeckle:
+						v *= 1 + opts.Amount*rng.NormFloat64()
+					case noisePoisson:
+						v = poisson(rng, v*opts.Amount) / opts.Amount
+					case noiseUniform:
+						v += opts.Amount * (2*rng.Float64() - 1)
+					}
+					out[c] = clampUint8(v)
+				}
+			}
+		}
+	})
+	return result
+}
+
+func (a *App) HandleNoise(base64str string, opts NoiseOptions) string {
+	if err := opts.validate(); err != nil {
+		a.showInvalidParameters(err)
+		return ""
+	}
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	return a.refFromImage(base64str, addNoise(m, opts))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func flatImage(v uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = v, v, v, 200
+	}
+	return img
+}
+
+func TestNoiseIsDeterministic(t *testing.T) {
+	defer func(n int) { maxWorkers = n }(maxWorkers)
+	img := flatImage(128)
+	for _, kind := range AllNoiseKinds {
+		opts := NoiseOptions{Kind: kind.Value, Amount: noiseLimits[kind.Value] / 4, Seed: 42}
+		maxWorkers = 1
+		first := addNoise(img, opts).(*image.NRGBA)
+		maxWorkers = 4
+		second := addNoise(img, opts).(*image.NRGBA)
+		if !bytes.Equal(first.Pix, second.Pix) {
+			t.Errorf("%s: same seed gave different noise", kind.Value)
+		}
+		opts.Seed = 43
+		if other := addNoise(img, opts).(*image.NRGBA); bytes.Equal(first.Pix, other.Pix) {
+			t.Errorf("%s: different seeds gave the same noise", kind.Value)
+		}
+		for i := 3; i < len(first.Pix); i += 4 {
+			if first.Pix[i] != 200 {
+				t.Fatalf("%s: alpha changed to %d", kind.Value, first.Pix[i])
+			}
+		}
+	}
+}
+
+// channelStats returns mean and standard deviation of the red channel.
+func channelStats(img *image.NRGBA) (mean, std float64) {
+	var sum, sumSq float64
+	n := float64(len(img.Pix) / 4)
+	for i := 0; i < len(img.Pix); i += 4 {
+		v := float64(img.Pix[i])
+		sum += v
+		sumSq += v * v
+	}
+	mean = sum / n
+	return mean, math.Sqrt(sumSq/n - mean*mean)
+}
+
+func TestNoiseStatistics(t *testing.T) {
+	tests := []struct {
+		opts     NoiseOptions
+		mean     float64
+		std      float64
+		maxError float64
+	}{
+		{NoiseOptions{Kind: noiseGaussian, Amount: 10}, 100, 10, 0.5},
+		{NoiseOptions{Kind: noiseSpeckle, Amount: 0.1}, 100, 10, 0.5},
+		{NoiseOptions{Kind: noiseUniform, Amount: 30}, 100, 30 / math.Sqrt(3), 0.5},
+		// variance of Poisson(v * k) / k is v / k
+		{NoiseOptions{Kind: noisePoisson, Amount: 1}, 100, 10, 0.5},
+		{NoiseOptions{Kind: noisePoisson, Amount: 0.1}, 100, math.Sqrt(1000), 1.5},
+	}
+	for _, tt := range tests {
+		mean, std := channelStats(addNoise(flatImage(100), tt.opts).(*image.NRGBA))
+		if math.Abs(mean-tt.mean) > tt.maxError || math.Abs(std-tt.std) > tt.maxError {
+			t.Errorf("%s %g: mean %.2f std %.2f, want %g and %.2f",
+				tt.opts.Kind, tt.opts.Amount, mean, std, tt.mean, tt.std)
+		}
+	}
+}
+
+func TestSaltAndPepperDensity(t *testing.T) {
+	out := addNoise(flatImage(128), NoiseOptions{Kind: noiseSaltPepper, Amount: 0.2, Seed: 7}).(*image.NRGBA)
+	var salt, pepper int
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			switch out.NRGBAAt(x, y) {
+			case color.NRGBA{255, 255, 255, 200}:
+				salt++
+			case color.NRGBA{0, 0, 0, 200}:
+				pepper++
+			case color.NRGBA{128, 128, 128, 200}:
+			default:
+				t.Fatalf("unexpected pixel %v", out.NRGBAAt(x, y))
+			}
+		}
+	}
+	if density := float64(salt+pepper) / 10000; math.Abs(density-0.2) > 0.02 {
+		t.Errorf("density %.3f, want 0.2", density)
+	}
+	if math.Abs(float64(salt-pepper)) > 0.2*float64(salt+pepper) {
+		t.Errorf("salt %d and pepper %d should be about equal", salt, pepper)
+	}
+}
+
+func TestNoiseOptionsValidate(t *testing.T) {
+	invalid := []NoiseOptions{
+		{Kind: "pink", Amount: 1},
+		{Kind: noiseSaltPepper, Amount: 1.5},
+		{Kind: noiseGaussian, Amount: -1},
+		{Kind: noisePoisson, Amount: 0},
+		{Kind: noiseUniform, Amount: math.NaN()},
+	}
+	for _, opts := range invalid {
+		if opts.validate() == nil {
+			t.Errorf("%+v should be rejected", opts)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+)
+
+// ParamType is the kind of value an operation parameter takes. Int and float
+// parameters are limited to [Min, Max], enum parameters to Options. Points
+// parameters are curve points written as "x,y x,y ...". Text parameters are
+// free text the operation parses itself, such as a kernel.
+type ParamType string
+
+const (
+	paramInt    ParamType = "int"
+	paramFloat  ParamType = "float"
+	paramBool   ParamType = "bool"
+	paramEnum   ParamType = "enum"
+	paramPoints ParamType = "points"
+	paramText   ParamType = "text"
+)
+
+var AllParamTypes = []struct {
+	Value  ParamType
+	TSName string
+}{
+	{paramInt, "int"},
+	{paramFloat, "float"},
+	{paramBool, "bool"},
+	{paramEnum, "enum"},
+	{paramPoints, "points"},
+	{paramText, "text"},
+}
+
+// ParamSpec describes one parameter so the frontend can build a form field
+// for it.
+type ParamSpec struct {
+	Name    string    `json:"name"`
+	Label   string    `json:"label"`
+	Type    ParamType `json:"type"`
+	Min     float64   `json:"min"`
+	Max     float64   `json:"max"`
+	Default any       `json:"default"`
+	Options []string  `json:"options"`
+}
+
+// OperationInfo is what ListOperations reports about an operation.
+type OperationInfo struct {
+	Name     string      `json:"name"`
+	Label    string      `json:"label"`
+	Category string      `json:"category"`
+	Params   []ParamSpec `json:"params"`
+}
+
+// opParams holds resolved parameter values: int, float64, bool, string for
+// enum and text parameters or []CurvePoint according to the spec, with
+// defaults filled in.
+type opParams map[string]any
+
+func (p opParams) int(name string) int       { return p[name].(int) }
+func (p opParams) float(name string) float64 { return p[name].(float64) }
+func (p opParams) bool(name string) bool     { return p[name].(bool) }
+func (p opParams) string(name string) string { return p[name].(string) }
+func (p opParams) uint8(name string) uint8   { return uint8(p.int(name)) }
+func (p opParams) points(name string) []CurvePoint {
+	return p[name].([]CurvePoint)
+}
+
+type registeredOperation struct {
+	OperationInfo
+	// apply runs the operation. Ranges and enums are checked before it is
+	// called, it only has to check constraints between parameters.
+	apply func(img image.Image, p opParams) (image.Image, error)
+}
+
+var errUnknownOperation = errors.New("unknown operation")
+
+func intParam(name, label string, minVal, maxVal, def int) ParamSpec {
+	return ParamSpec{Name: name, Label: label, Type: paramInt, Min: float64(minVal), Max: float64(maxVal), Default: def}
+}
+
+func floatParam(name, label string, minVal, maxVal, def float64) ParamSpec {
+	return ParamSpec{Name: name, Label: label, Type: paramFloat, Min: minVal, Max: maxVal, Default: def}
+}
+
+func boolParam(name, label string, def bool) ParamSpec {
+	return ParamSpec{Name: name, Label: label, Type: paramBool, Default: def}
+}
+
+func pointsParam(name, label, def string) ParamSpec {
+	return ParamSpec{Name: name, Label: label, Type: paramPoints, Default: def}
+}
+
+func textParam(name, label, def string) ParamSpec {
+	return ParamSpec{Name: name, Label: label, Type: paramText, Default: def}
+}
+
+// enumParam defaults to the first option.
+func enumParam[T ~string](name, label string, options ...T) ParamSpec {
+	spec := ParamSpec{Name: name, Label: label, Type: paramEnum, Default: string(options[0])}
+	for _, o := range options {
+		spec.Options = append(spec.Options, string(o))
+	}
+	return spec
+}
+
+// resolve checks a value sent by the frontend, where every number is a
+// float64, and converts it to the type of the parameter.
+func (s ParamSpec) resolve(v any) (any, error) {
+	switch s.Type {
+	case paramBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be true or false", s.Name)
+		}
+		return b, nil
+	case paramEnum:
+		str, ok := v.(string)
+		if !ok || !slices.Contains(s.Options, str) {
+			return nil, fmt.Errorf("%s must be one of %v", s.Name, s.Options)
+		}
+		return str, nil
+	case paramPoints:
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be points written as x,y x,y ...", s.Name)
+		}
+		points, err := parseCurvePoints(str)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		return points, nil
+	case paramText:
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be text", s.Name)
+		}
+		return str, nil
+	}
+	var f float64
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case int:
+		f = float64(n)
+	default:
+		return nil, fmt.Errorf("%s must be a number", s.Name)
+	}
+	if math.IsNaN(f) || f < s.Min || f > s.Max {
+		return nil, fmt.Errorf("%s must be between %g and %g, got %g", s.Name, s.Min, s.Max, f)
+	}
+	if s.Type == paramInt {
+		if f != math.Trunc(f) {
+			return nil, fmt.Errorf("%s must be a whole number, got %g", s.Name, f)
+		}
+		return int(f), nil
+	}
+	return f, nil
+}
+
+func resolveParams(specs []ParamSpec, params map[string]any) (opParams, error) {
+	resolved := make(opParams, len(specs))
+	for _, spec := range specs {
+		v, ok := params[spec.Name]
+		if !ok || v == nil {
+			v = spec.Default
+		}
+		value, err := spec.resolve(v)
+		if err != nil {
+			return nil, err
+		}
+		resolved[spec.Name] = value
+	}
+	for name := range params {
+		if !slices.ContainsFunc(specs, func(s ParamSpec) bool { return s.Name == name }) {
+			return nil, fmt.Errorf("unknown parameter '%s'", name)
+		}
+	}
+	return resolved, nil
+}
+
+// noParams adapts a filter without parameters.
+func noParams(filter func(image.Image) image.Image) func(image.Image, opParams) (image.Image, error) {
+	return func(img image.Image, _ opParams) (image.Image, error) {
+		return filter(img), nil
+	}
+}
+
+func findOperation(name string) (registeredOperation, bool) {
+	i := slices.IndexFunc(operations, func(op registeredOperation) bool { return op.Name == name })
+	if i < 0 {
+		return registeredOperation{}, false
+	}
+	return operations[i], true
+}
+
+// applyOperation runs the named operation with the given parameters, missing
+// parameters take their default.
+func applyOperation(img image.Image, name string, params map[string]any) (image.Image, error) {
+	op, ok := findOperation(name)
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", errUnknownOperation, name)
+	}
+	resolved, err := resolveParams(op.Params, params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	result, err := op.apply(img, resolved)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return result, nil
+}
+
+// ListOperations describes every operation ApplyOperation accepts, in the
+// order they should be offered.
+func (a *App) ListOperations() []OperationInfo {
+	infos := make([]OperationInfo, len(operations))
+	for i, op := range operations {
+		infos[i] = op.OperationInfo
+	}
+	return infos
+}
+
+// ApplyOperation runs a registered operation on an image reference or data
+// URL and returns the result in the same form. Unlike the Handle* methods it
+// reports problems as errors instead of dialogs.
+func (a *App) ApplyOperation(base64str string, name string, params map[string]any) (string, error) {
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		return "", err
+	}
+	result, err := applyOperation(m, name, params)
+	if err != nil {
+		return "", err
+	}
+	return a.refFromImage(base64str, result), nil
+}
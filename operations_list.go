@@ -0,0 +1,586 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+const (
+	categorySmoothing    = "smoothing"
+	categorySharpening   = "sharpening"
+	categoryConvolution  = "convolution"
+	categoryEdges        = "edges"
+	categoryFrequency    = "frequency"
+	categoryBinarisation = "binarisation"
+	categoryMorphology   = "morphology"
+	categoryHistogram    = "histogram"
+	categoryNoise        = "noise"
+	categoryPointWise    = "point-wise"
+)
+
+// noiseOperation registers one kind of noise with its amount under a name
+// that says what the amount means.
+func noiseOperation(kind NoiseKind, name, label string, amount ParamSpec) registeredOperation {
+	return registeredOperation{
+		OperationInfo: OperationInfo{
+			Name: name, Label: label, Category: categoryNoise,
+			Params: []ParamSpec{amount, intParam("seed", "Seed", 0, math.MaxInt32, 1)},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := NoiseOptions{Kind: kind, Amount: p.float(amount.Name), Seed: int64(p.int("seed"))}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return addNoise(img, opts), nil
+		},
+	}
+}
+
+// toneOperation registers one tone transform. The luminance switch is added
+// to the params of every transform.
+func toneOperation(name, label string, options func(opParams) ToneOptions, params ...ParamSpec) registeredOperation {
+	return registeredOperation{
+		OperationInfo: OperationInfo{
+			Name: name, Label: label, Category: categoryPointWise,
+			Params: append(params, boolParam("luminance", "Luminance only", false)),
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := options(p)
+			opts.Luminance = p.bool("luminance")
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return toneTransform(img, opts), nil
+		},
+	}
+}
+
+// operations is the registry behind ListOperations and ApplyOperation.
+var operations = []registeredOperation{
+	{
+		OperationInfo: OperationInfo{Name: "average", Label: "Average 3x3", Category: categorySmoothing},
+		apply:         noParams(ApplyAveragingFilter),
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "median", Label: "Median", Category: categorySmoothing,
+			Params: []ParamSpec{
+				intParam("size", "Window size (odd)", minMedianWindow, maxMedianWindow, 3),
+				enumParam("shape", "Window shape", medianSquare, medianCircle),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			if p.int("size")%2 == 0 {
+				return nil, fmt.Errorf("window size must be odd, got %d", p.int("size"))
+			}
+			return medianFilter(img, p.int("size"), MedianShape(p.string("shape"))), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "gaussian", Label: "Gaussian blur", Category: categorySmoothing,
+			Params: []ParamSpec{
+				floatParam("sigma", "Sigma", 0.1, 100, 2),
+				intParam("radius", "Radius (0 = 3 sigma)", 0, maxGaussianRadius, 0),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return gaussianBlur(img, p.float("sigma"), p.int("radius")), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "bilateral", Label: "Bilateral filter", Category: categorySmoothing,
+			Params: []ParamSpec{
+				floatParam("spatialSigma", "Spatial sigma", 0.5, maxBilateralSigma, defaultBilateralSpatial),
+				floatParam("rangeSigma", "Range sigma", 1, 255, defaultBilateralRange),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return bilateralFilter(img, p.float("spatialSigma"), p.float("rangeSigma")), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "kuwahara", Label: "Kuwahara filter", Category: categorySmoothing,
+			Params: []ParamSpec{
+				intParam("radius", "Radius", 1, maxKuwaharaRadius, defaultKuwaharaRadius),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return kuwaharaFilter(img, p.int("radius")), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "diffusion", Label: "Anisotropic diffusion", Category: categorySmoothing,
+			Params: []ParamSpec{
+				intParam("iterations", "Iterations", 1, maxDiffusionSteps, defaultDiffusionSteps),
+				floatParam("kappa", "Kappa", 1, 255, defaultDiffusionKappa),
+				enumParam("conduction", "Conduction", conductionExponential, conductionQuadratic),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return anisotropicDiffusion(img, p.int("iterations"), p.float("kappa"), Conduction(p.string("conduction"))), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "sharpen", Label: "Sharpen", Category: categorySharpening,
+			Params: []ParamSpec{
+				enumParam("method", "Method", sharpenUnsharp, sharpenHighPass),
+				floatParam("amount", "Amount", 0, 10, 1),
+				floatParam("radius", "Radius (blur sigma)", 0.1, maxGaussianRadius/3, 2),
+				floatParam("threshold", "Threshold", 0, 255, 0),
+				boolParam("luminance", "Luminance only", true),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return sharpen(img, SharpenOptions{
+				Method:    SharpenMethod(p.string("method")),
+				Amount:    p.float("amount"),
+				Radius:    p.float("radius"),
+				Threshold: p.float("threshold"),
+				Luminance: p.bool("luminance"),
+			}), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "convolution", Label: "Custom kernel", Category: categoryConvolution,
+			Params: []ParamSpec{
+				textParam("kernel", "Kernel (rows separated by ;)", "1 1 1; 1 1 1; 1 1 1"),
+				floatParam("divisor", "Divisor (0 = sum of kernel)", -maxKernelDivisor, maxKernelDivisor, 0),
+				floatParam("bias", "Bias", -255, 255, 0),
+				enumParam("border", "Border (constant is black)", borderClamp, borderWrap, borderReflect, borderConstant),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			kernel, err := parseKernel(p.string("kernel"))
+			if err != nil {
+				return nil, err
+			}
+			opts := ConvolutionOptions{
+				Kernel:  kernel,
+				Divisor: p.float("divisor"),
+				Bias:    p.float("bias"),
+				Border:  BorderMode(p.string("border")),
+			}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return convolve(img, opts), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "edges", Label: "Edge detection", Category: categoryEdges,
+			Params: []ParamSpec{
+				enumParam("operator", "Operator",
+					edgeSobel, edgePrewitt, edgeRoberts, edgeScharr, edgeLaplacian4, edgeLaplacian8, edgeLoG),
+				enumParam("output", "Output", edgeMagnitude, edgeX, edgeY, edgeDirection),
+				floatParam("sigma", "Sigma (Laplacian of Gaussian)", 0.1, 20, 1.4),
+				boolParam("normalize", "Stretch to the strongest edge", false),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := EdgeOptions{
+				Operator:  EdgeOperator(p.string("operator")),
+				Output:    EdgeOutput(p.string("output")),
+				Sigma:     p.float("sigma"),
+				Normalize: p.bool("normalize"),
+			}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return detectEdges(img, opts), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "canny", Label: "Canny edges", Category: categoryEdges,
+			Params: []ParamSpec{
+				floatParam("sigma", "Sigma (0 = no smoothing)", 0, 20, 1.4),
+				enumParam("thresholds", "Thresholds", cannyManual, cannyMedian, cannyOtsu),
+				floatParam("low", "Low threshold", 0, 1500, 50),
+				floatParam("high", "High threshold", 0, 1500, 150),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := CannyOptions{
+				Sigma:      p.float("sigma"),
+				Low:        p.float("low"),
+				High:       p.float("high"),
+				Thresholds: CannyThresholds(p.string("thresholds")),
+			}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return canny(img, opts), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "spectrum", Label: "Fourier spectrum", Category: categoryFrequency,
+			Params: []ParamSpec{
+				enumParam("kind", "Spectrum", spectrumMagnitude, spectrumPhase),
+				enumParam("channels", "Channels", frequencyLuminance, frequencyRgb),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return spectrumImage(img, SpectrumKind(p.string("kind")), FrequencyChannels(p.string("channels"))), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "frequencyFilter", Label: "Frequency filter", Category: categoryFrequency,
+			Params: []ParamSpec{
+				enumParam("pass", "Filter", passLow, passHigh, passBand, passNotch),
+				enumParam("shape", "Shape", shapeIdeal, shapeButterworth, shapeGaussian),
+				floatParam("cutoff", "Cutoff or band centre (1 = Nyquist)", 0.01, 1.5, 0.2),
+				floatParam("width", "Band width", 0.01, 1.5, 0.1),
+				intParam("order", "Butterworth order", 1, 10, 2),
+				textParam("notches", "Notches (u,v u,v ...)", "0.5,0"),
+				enumParam("channels", "Channels", frequencyLuminance, frequencyRgb),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := FrequencyFilterOptions{
+				Pass:     FrequencyPass(p.string("pass")),
+				Shape:    FilterShape(p.string("shape")),
+				Cutoff:   p.float("cutoff"),
+				Width:    p.float("width"),
+				Order:    p.int("order"),
+				Channels: FrequencyChannels(p.string("channels")),
+			}
+			if opts.Pass == passNotch {
+				notches, err := parseNotches(p.string("notches"))
+				if err != nil {
+					return nil, err
+				}
+				opts.Notches = notches
+			}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return frequencyFilter(img, opts), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "threshold", Label: "Manual threshold", Category: categoryBinarisation,
+			Params: []ParamSpec{
+				intParam("threshold", "Threshold", 0, 255, 128),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return binarizeManual(img, p.uint8("threshold")), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "percentBlack", Label: "Percent black", Category: categoryBinarisation,
+			Params: []ParamSpec{
+				floatParam("percent", "Percent", 0, 99, 50),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return binalizePercentBlack(img, p.float("percent")), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "meanIterative", Label: "Mean iterative selection", Category: categoryBinarisation,
+			Params: []ParamSpec{
+				intParam("maxIterations", "Max iterations", 0, 100, 50),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return binalizeMeanIterative(img, p.int("maxIterations")), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{Name: "otsu", Label: "Otsu", Category: categoryBinarisation},
+		apply:         noParams(binarizeOtsu),
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "niblack", Label: "Niblack", Category: categoryBinarisation,
+			Params: []ParamSpec{
+				intParam("windowSize", "Window size", 3, 101, 15),
+				floatParam("k", "k", -2, 2, -0.2),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return binarizeNiblack(img, p.int("windowSize"), p.float("k")), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "bernsen", Label: "Bernsen", Category: categoryBinarisation,
+			Params: []ParamSpec{
+				intParam("windowSize", "Window size", 3, 101, 15),
+				intParam("contrastThreshold", "Contrast threshold", 0, 255, 15),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return binarizeBernsen(img, p.int("windowSize"), p.uint8("contrastThreshold")), nil
+		},
+	},
+	{
+		// the green percentage HandleGrassTask shows has no place in an image
+		// result and is left out
+		OperationInfo: OperationInfo{
+			Name: "grass", Label: "Grass: largest dark region", Category: categoryBinarisation,
+			Params: []ParamSpec{
+				intParam("greenThreshold", "Green threshold", 0, 255, 20),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			m, _ := grassImage(img, p.uint8("greenThreshold"))
+			return m, nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{Name: "dilation", Label: "Dilation", Category: categoryMorphology},
+		apply:         noParams(dilation),
+	},
+	{
+		OperationInfo: OperationInfo{Name: "erosion", Label: "Erosion", Category: categoryMorphology},
+		apply:         noParams(erosion),
+	},
+	{
+		OperationInfo: OperationInfo{Name: "opening", Label: "Opening", Category: categoryMorphology},
+		apply:         noParams(opening),
+	},
+	{
+		OperationInfo: OperationInfo{Name: "closing", Label: "Closing", Category: categoryMorphology},
+		apply:         noParams(closing),
+	},
+	{
+		OperationInfo: OperationInfo{Name: "hitOrMiss", Label: "Hit or miss", Category: categoryMorphology},
+		apply:         noParams(hitOrMiss),
+	},
+	{
+		OperationInfo: OperationInfo{Name: "stretch", Label: "Stretch histogram", Category: categoryHistogram},
+		apply:         noParams(stretchHistogram),
+	},
+	{
+		OperationInfo: OperationInfo{Name: "equalize", Label: "Equalize histogram", Category: categoryHistogram},
+		apply:         noParams(equalizeHistogram),
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "rgbArithmetic", Label: "RGB arithmetic", Category: categoryPointWise,
+			Params: []ParamSpec{
+				enumParam("operation", "Operation", operationNames...),
+				floatParam("r", "Red", 0, 255, 0),
+				floatParam("g", "Green", 0, 255, 0),
+				floatParam("b", "Blue", 0, 255, 0),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			op, _ := parseOperation(p.string("operation"))
+			pwrv := pointWiseRgbValues{r: p.float("r"), g: p.float("g"), b: p.float("b"), op: op}
+			if err := pwrv.validate(); err != nil {
+				return nil, err
+			}
+			return newRgbImage(pwrv, img), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "alpha", Label: "Set alpha", Category: categoryPointWise,
+			Params: []ParamSpec{intParam("alpha", "Alpha", 0, 255, 255)},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return newAlphaImage(p.uint8("alpha"), img), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "grayscale", Label: "Grayscale", Category: categoryPointWise,
+			Params: []ParamSpec{
+				enumParam("method", "Method", grayAverage, grayRec601, grayRec709, grayRec2020,
+					grayLightness, grayLuminosity, grayChannel, grayCustom, grayDecolorize),
+				enumParam("channel", "Channel (single channel)", channelRed, channelGreen, channelBlue),
+				floatParam("red", "Red weight (custom)", 0, 1, 0.299),
+				floatParam("green", "Green weight (custom)", 0, 1, 0.587),
+				floatParam("blue", "Blue weight (custom)", 0, 1, 0.114),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := GrayOptions{
+				Method:  GrayMethod(p.string("method")),
+				Channel: ToneChannel(p.string("channel")),
+				Weights: [3]float64{p.float("red"), p.float("green"), p.float("blue")},
+			}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return grayscale(img, opts), nil
+		},
+	},
+	toneOperation("brightness", "Brightness", func(p opParams) ToneOptions {
+		return ToneOptions{Transform: toneBrightness, Amount: p.float("offset")}
+	}, floatParam("offset", "Offset", toneLimits[toneBrightness][0], toneLimits[toneBrightness][1], 20)),
+	toneOperation("contrast", "Contrast", func(p opParams) ToneOptions {
+		return ToneOptions{Transform: toneContrast, Amount: p.float("factor"), Pivot: ContrastPivot(p.string("pivot"))}
+	}, floatParam("factor", "Factor", toneLimits[toneContrast][0], toneLimits[toneContrast][1], 1.2),
+		enumParam("pivot", "Pivot", pivotMidGrey, pivotMean)),
+	toneOperation("gamma", "Gamma correction", func(p opParams) ToneOptions {
+		return ToneOptions{Transform: toneGamma, Amount: p.float("gamma")}
+	}, floatParam("gamma", "Gamma", toneLimits[toneGamma][0], toneLimits[toneGamma][1], 2.2)),
+	toneOperation("logTransform", "Logarithmic transform", func(p opParams) ToneOptions {
+		return ToneOptions{Transform: toneLog, Amount: p.float("strength")}
+	}, floatParam("strength", "Strength", toneLimits[toneLog][0], toneLimits[toneLog][1], 10)),
+	toneOperation("expTransform", "Exponential transform", func(p opParams) ToneOptions {
+		return ToneOptions{Transform: toneExp, Amount: p.float("strength")}
+	}, floatParam("strength", "Strength", toneLimits[toneExp][0], toneLimits[toneExp][1], 10)),
+	toneOperation("powerLaw", "Power-law transform", func(p opParams) ToneOptions {
+		return ToneOptions{Transform: tonePower, Amount: p.float("exponent"), Scale: p.float("scale")}
+	}, floatParam("exponent", "Exponent", toneLimits[tonePower][0], toneLimits[tonePower][1], 0.5),
+		floatParam("scale", "Scale", 0, maxPowerScale, 1)),
+	{
+		OperationInfo: OperationInfo{Name: "invert", Label: "Invert", Category: categoryPointWise},
+		apply: noParams(func(m image.Image) image.Image {
+			return applyLut(m, invertLut(), false)
+		}),
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "posterize", Label: "Posterize", Category: categoryPointWise,
+			Params: []ParamSpec{intParam("levels", "Levels per channel", 2, maxPosterizeLevels, 4)},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return applyLut(img, posterizeLut(p.int("levels")), false), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "solarize", Label: "Solarize", Category: categoryPointWise,
+			Params: []ParamSpec{intParam("threshold", "Threshold", 0, 255, 128)},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return applyLut(img, solarizeLut(p.uint8("threshold")), false), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "bitPlane", Label: "Bit plane", Category: categoryPointWise,
+			Params: []ParamSpec{intParam("plane", "Plane (0 is the least significant)", 0, bitPlaneCount-1, bitPlaneCount-1)},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return bitPlanes(img)[p.int("plane")], nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "keepBitPlanes", Label: "Keep bit planes", Category: categoryPointWise,
+			Params: []ParamSpec{intParam("mask", "Mask of planes to keep", 0, 255, 0xf0)},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			return keepBitPlanes(img, p.uint8("mask")), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "levels", Label: "Levels", Category: categoryPointWise,
+			Params: []ParamSpec{
+				enumParam("channel", "Channel", channelComposite, channelRed, channelGreen, channelBlue),
+				floatParam("inputBlack", "Input black", 0, 255, 0),
+				floatParam("inputWhite", "Input white", 0, 255, 255),
+				floatParam("gamma", "Gamma", minLevelsGamma, maxLevelsGamma, 1),
+				floatParam("outputBlack", "Output black", 0, 255, 0),
+				floatParam("outputWhite", "Output white", 0, 255, 255),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := LevelsOptions{
+				Channel:    ToneChannel(p.string("channel")),
+				InputBlack: p.float("inputBlack"), InputWhite: p.float("inputWhite"),
+				Gamma:       p.float("gamma"),
+				OutputBlack: p.float("outputBlack"), OutputWhite: p.float("outputWhite"),
+			}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return levels(img, opts), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "curves", Label: "Curves", Category: categoryPointWise,
+			Params: []ParamSpec{
+				enumParam("channel", "Channel", channelComposite, channelRed, channelGreen, channelBlue),
+				pointsParam("points", "Points (x,y x,y ...)", "0,0 64,48 192,208 255,255"),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := CurvesOptions{Channel: ToneChannel(p.string("channel")), Points: p.points("points")}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return curves(img, opts), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "hueSaturation", Label: "Hue, saturation and lightness", Category: categoryPointWise,
+			Params: []ParamSpec{
+				enumParam("model", "Colour model", modelHsv, modelHsl),
+				floatParam("hue", "Hue rotation (degrees)", -180, 180, 30),
+				floatParam("saturation", "Saturation scale", 0, maxSaturationScale, 1),
+				floatParam("lightness", "Lightness / value", -1, 1, 0),
+				boolParam("restrict", "Restrict to hue range", false),
+				floatParam("rangeCenter", "Hue range centre", 0, 360, 0),
+				floatParam("rangeWidth", "Hue range width", 0, 360, 60),
+				floatParam("rangeFeather", "Hue range feather", 0, 180, 30),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := HueSaturationOptions{
+				Model: ColorModel(p.string("model")),
+				Hue:   p.float("hue"), Saturation: p.float("saturation"), Lightness: p.float("lightness"),
+				Restrict:    p.bool("restrict"),
+				RangeCenter: p.float("rangeCenter"), RangeWidth: p.float("rangeWidth"), RangeFeather: p.float("rangeFeather"),
+			}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return hueSaturation(img, opts), nil
+		},
+	},
+	{
+		OperationInfo: OperationInfo{
+			Name: "colorize", Label: "Colourise", Category: categoryPointWise,
+			Params: []ParamSpec{
+				enumParam("model", "Colour model", modelHsl, modelHsv),
+				floatParam("hue", "Hue (degrees)", 0, 360, 30),
+				floatParam("saturation", "Saturation", 0, 1, 0.5),
+				floatParam("lightness", "Lightness / value", -1, 1, 0),
+			},
+		},
+		apply: func(img image.Image, p opParams) (image.Image, error) {
+			opts := HueSaturationOptions{
+				Model: ColorModel(p.string("model")), Colorize: true,
+				Hue: p.float("hue"), Saturation: p.float("saturation"), Lightness: p.float("lightness"),
+			}
+			if err := opts.validate(); err != nil {
+				return nil, err
+			}
+			return hueSaturation(img, opts), nil
+		},
+	},
+	noiseOperation(noiseSaltPepper, "saltPepper", "Salt and pepper noise",
+		floatParam("density", "Density", 0, noiseLimits[noiseSaltPepper], 0.05)),
+	noiseOperation(noiseGaussian, "gaussianNoise", "Gaussian noise",
+		floatParam("sigma", "Sigma", 0, noiseLimits[noiseGaussian], 20)),
+	noiseOperation(noiseSpeckle, "speckle", "Speckle noise",
+		floatParam("sigma", "Sigma (relative)", 0, noiseLimits[noiseSpeckle], 0.2)),
+	noiseOperation(noisePoisson, "poissonNoise", "Poisson noise",
+		floatParam("photons", "Photons per level (lower is noisier)", 0.01, noiseLimits[noisePoisson], 1)),
+	noiseOperation(noiseUniform, "uniformNoise", "Uniform noise",
+		floatParam("amplitude", "Amplitude", 0, noiseLimits[noiseUniform], 30)),
+}
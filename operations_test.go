@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+func TestOperationsRunWithDefaults(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 9, 7))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 13)
+	}
+	names := map[string]bool{}
+	for _, op := range operations {
+		if names[op.Name] {
+			t.Errorf("operation %s registered twice", op.Name)
+		}
+		names[op.Name] = true
+		for _, spec := range op.Params {
+			if _, err := spec.resolve(spec.Default); err != nil {
+				t.Errorf("%s: default does not fit its own spec: %v", op.Name, err)
+			}
+		}
+		out, err := applyOperation(img, op.Name, nil)
+		if err != nil {
+			t.Errorf("%s: %v", op.Name, err)
+			continue
+		}
+		if out.Bounds().Empty() {
+			t.Errorf("%s: empty result", op.Name)
+		}
+	}
+}
+
+func TestApplyOperationParams(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	img.SetGray(1, 1, color.Gray{200})
+
+	for threshold, want := range map[float64]uint8{100: 255, 220: 0} {
+		out, err := applyOperation(img, "threshold", map[string]any{"threshold": threshold})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.(*image.Gray).GrayAt(1, 1).Y; got != want {
+			t.Errorf("threshold %g: got %d, want %d", threshold, got, want)
+		}
+	}
+
+	invalid := []struct {
+		name   string
+		params map[string]any
+	}{
+		{"threshold", map[string]any{"threshold": float64(300)}},
+		{"threshold", map[string]any{"threshold": 12.5}},
+		{"threshold", map[string]any{"threshold": "high"}},
+		{"threshold", map[string]any{"level": float64(10)}},
+		{"median", map[string]any{"size": float64(4)}},
+		{"median", map[string]any{"shape": "diamond"}},
+		{"sharpen", map[string]any{"luminance": float64(1)}},
+		{"edges", map[string]any{"operator": "laplacian4", "output": "direction"}},
+		{"convolution", map[string]any{"kernel": "1 1; 1 1"}},
+		{"convolution", map[string]any{"kernel": "1 x 1"}},
+		{"convolution", map[string]any{"kernel": float64(1)}},
+		{"frequencyFilter", map[string]any{"pass": "notch", "notches": ""}},
+		{"frequencyFilter", map[string]any{"pass": "notch", "notches": "0.5"}},
+	}
+	for _, tt := range invalid {
+		if _, err := applyOperation(img, tt.name, tt.params); err == nil {
+			t.Errorf("%s %v: expected an error", tt.name, tt.params)
+		}
+	}
+	if _, err := applyOperation(img, "blur everything", nil); !errors.Is(err, errUnknownOperation) {
+		t.Errorf("expected errUnknownOperation, got %v", err)
+	}
+}
+
+func TestApplyOperationTextParams(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 5, 5))
+	img.SetGray(2, 2, color.Gray{200})
+	out, err := applyOperation(img, "convolution", map[string]any{"kernel": "0 0 0\n0 0 1; 0,0,0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the kernel reads the right neighbour, so the bright pixel moves left
+	if r, _, _, _ := out.At(1, 2).RGBA(); r>>8 != 200 {
+		t.Errorf("shifted pixel is %d, want 200", r>>8)
+	}
+	if _, err := applyOperation(img, "frequencyFilter", map[string]any{"pass": "notch", "notches": "0.5,0 0,-0.25"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMorphologyHandlersMatchOperations(t *testing.T) {
+	a := NewApp()
+	img := image.NewGray(image.Rect(0, 0, 9, 9))
+	img.SetGray(1, 1, color.Gray{255})
+	for y := 4; y < 8; y++ {
+		for x := 4; x < 8; x++ {
+			img.SetGray(x, y, color.Gray{255})
+		}
+	}
+	ref := a.images.put(img)
+	for name, handler := range map[string]func(string) string{"opening": a.HandleOpening, "closing": a.HandleClosing} {
+		got, err := a.imageFromRef(handler(ref))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := applyOperation(img, name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(toGray(got).Pix, toGray(want).Pix) {
+			t.Errorf("%s: handler and operation differ", name)
+		}
+	}
+	if out, _ := applyOperation(img, "opening", nil); out.(*image.Gray).GrayAt(1, 1).Y != 0 {
+		t.Error("opening should remove an isolated pixel")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// tileRows is the height of the horizontal tiles handed out to workers. Tiles
+// are taken from a shared counter, so a slow tile does not hold up the rest.
+const tileRows = 16
+
+// maxWorkers limits the goroutines used by parallelRows, 0 means GOMAXPROCS.
+// The benchmarks set it to 1 to measure the serial speed.
+var maxWorkers = 0
+
+// parallelRows calls fn for consecutive row ranges [y0, y1) covering 0..h,
+// from several goroutines. fn must only write rows inside its range.
+func parallelRows(h int, fn func(y0, y1 int)) {
+	tiles := (h + tileRows - 1) / tileRows
+	workers := maxWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, tiles)
+	if workers <= 1 {
+		if h > 0 {
+			fn(0, h)
+		}
+		return
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				tile := int(next.Add(1) - 1)
+				if tile >= tiles {
+					return
+				}
+				fn(tile*tileRows, min((tile+1)*tileRows, h))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// toNRGBA returns the image as non-premultiplied 8-bit RGBA with the same
+// bounds. NRGBA images are returned as they are, so the result must not be
+// modified. RGBA and Gray are converted straight from their Pix slices and
+// give exactly what color.NRGBAModel would.
+func toNRGBA(img image.Image) *image.NRGBA {
+	if m, ok := img.(*image.NRGBA); ok {
+		return m
+	}
+	b := img.Bounds()
+	w := b.Dx()
+	result := image.NewNRGBA(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dst := result.Pix[y*result.Stride : y*result.Stride+4*w]
+			switch m := img.(type) {
+			case *image.RGBA:
+				src := m.Pix[m.PixOffset(b.Min.X, b.Min.Y+y):]
+				for x := 0; x < w; x++ {
+					unpremultiply(dst[4*x:4*x+4], src[4*x:4*x+4])
+				}
+			case *image.Gray:
+				src := m.Pix[m.PixOffset(b.Min.X, b.Min.Y+y):]
+				for x := 0; x < w; x++ {
+					dst[4*x], dst[4*x+1], dst[4*x+2], dst[4*x+3] = src[x], src[x], src[x], 0xff
+				}
+			default:
+				for x := 0; x < w; x++ {
+					c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+					dst[4*x], dst[4*x+1], dst[4*x+2], dst[4*x+3] = c.R, c.G, c.B, c.A
+				}
+			}
+		}
+	})
+	return result
+}
+
+// unpremultiply converts one RGBA pixel to NRGBA the way color.NRGBAModel
+// does.
+func unpremultiply(dst, src []uint8) {
+	a := src[3]
+	switch a {
+	case 0xff:
+		copy(dst, src[:4])
+	case 0:
+		dst[0], dst[1], dst[2], dst[3] = 0, 0, 0, 0
+	default:
+		a16 := uint32(a) * 0x101
+		for c := 0; c < 3; c++ {
+			dst[c] = uint8((uint32(src[c]) * 0x101 * 0xffff / a16) >> 8)
+		}
+		dst[3] = a
+	}
+}
+
+// toGray returns the luminance of the image with the same bounds, exactly as
+// color.GrayModel computes it. Gray images are returned as they are, so the
+// result must not be modified.
+func toGray(img image.Image) *image.Gray {
+	if m, ok := img.(*image.Gray); ok {
+		return m
+	}
+	b := img.Bounds()
+	w := b.Dx()
+	result := image.NewGray(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dst := result.Pix[y*result.Stride : y*result.Stride+w]
+			switch m := img.(type) {
+			case *image.NRGBA:
+				src := m.Pix[m.PixOffset(b.Min.X, b.Min.Y+y):]
+				for x := range dst {
+					p := src[4*x : 4*x+4]
+					dst[x] = grayOf(color.NRGBA{p[0], p[1], p[2], p[3]}.RGBA())
+				}
+			case *image.RGBA:
+				src := m.Pix[m.PixOffset(b.Min.X, b.Min.Y+y):]
+				for x := range dst {
+					p := src[4*x : 4*x+4]
+					dst[x] = grayOf(color.RGBA{p[0], p[1], p[2], p[3]}.RGBA())
+				}
+			default:
+				for x := range dst {
+					dst[x] = color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+				}
+			}
+		}
+	})
+	return result
+}
+
+// grayOf is color.GrayModel for the 16-bit premultiplied channels of a
+// colour, without going through the color.Color interface.
+func grayOf(r, g, b, _ uint32) uint8 {
+	return uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+}
+
+// premultiplied returns the 16-bit premultiplied r, g, b, a of every pixel as
+// RGBA() would, row after row without gaps. RGBA and NRGBA images are read
+// straight from Pix.
+func premultiplied(img image.Image) (pix []uint32, w, h int) {
+	b := img.Bounds()
+	w, h = b.Dx(), b.Dy()
+	pix = make([]uint32, 4*w*h)
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dst := pix[4*y*w : 4*(y+1)*w]
+			switch m := img.(type) {
+			case *image.RGBA:
+				row := m.Pix[m.PixOffset(b.Min.X, b.Min.Y+y):]
+				for i := range dst {
+					dst[i] = uint32(row[i]) * 0x101
+				}
+			case *image.NRGBA:
+				row := m.Pix[m.PixOffset(b.Min.X, b.Min.Y+y):]
+				for x := 0; x < w; x++ {
+					p := row[4*x : 4*x+4]
+					dst[4*x], dst[4*x+1], dst[4*x+2], dst[4*x+3] = color.NRGBA{p[0], p[1], p[2], p[3]}.RGBA()
+				}
+			default:
+				for x := 0; x < w; x++ {
+					dst[4*x], dst[4*x+1], dst[4*x+2], dst[4*x+3] = img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+				}
+			}
+		}
+	})
+	return pix, w, h
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelRowsCoversEveryRow(t *testing.T) {
+	defer func(n int) { maxWorkers = n }(maxWorkers)
+	for _, workers := range []int{0, 1, 3} {
+		maxWorkers = workers
+		for _, h := range []int{0, 1, tileRows - 1, tileRows, tileRows + 1, 10*tileRows + 3} {
+			visits := make([]atomic.Int32, h)
+			parallelRows(h, func(y0, y1 int) {
+				for y := y0; y < y1; y++ {
+					visits[y].Add(1)
+				}
+			})
+			for y := range visits {
+				if n := visits[y].Load(); n != 1 {
+					t.Errorf("workers %d, height %d: row %d visited %d times", workers, h, y, n)
+				}
+			}
+		}
+	}
+}
+
+// randomImages returns the same random content as NRGBA, RGBA, Gray, YCbCr
+// and as an NRGBA with bounds away from the origin.
+func randomImages(w, h int) map[string]image.Image {
+	rng := rand.New(rand.NewSource(1))
+	nrgba := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rng.Read(nrgba.Pix)
+	rgba := image.NewRGBA(nrgba.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), nrgba, image.Point{}, draw.Src)
+	gray := image.NewGray(nrgba.Bounds())
+	rng.Read(gray.Pix)
+	ycbcr := image.NewYCbCr(nrgba.Bounds(), image.YCbCrSubsampleRatio420)
+	rng.Read(ycbcr.Y)
+	rng.Read(ycbcr.Cb)
+	rng.Read(ycbcr.Cr)
+	offset := image.NewNRGBA(image.Rect(5, 7, 5+w, 7+h))
+	draw.Draw(offset, offset.Bounds(), nrgba, image.Point{}, draw.Src)
+	return map[string]image.Image{
+		"nrgba": nrgba, "rgba": rgba, "gray": gray, "ycbcr": ycbcr, "offset": offset,
+		"sub image": rgba.SubImage(image.Rect(3, 2, w-1, h-4)),
+	}
+}
+
+func TestConversionsMatchColorModels(t *testing.T) {
+	for name, img := range randomImages(37, 29) {
+		b := img.Bounds()
+		nrgba, gray := toNRGBA(img), toGray(img)
+		pix, w, _ := premultiplied(img)
+		if nrgba.Bounds() != b || gray.Bounds() != b {
+			t.Errorf("%s: bounds changed", name)
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := img.At(x, y)
+				if want := color.NRGBAModel.Convert(c); nrgba.NRGBAAt(x, y) != want {
+					t.Fatalf("%s: toNRGBA at %d,%d = %v, want %v", name, x, y, nrgba.NRGBAAt(x, y), want)
+				}
+				if want := color.GrayModel.Convert(c); gray.GrayAt(x, y) != want {
+					t.Fatalf("%s: toGray at %d,%d = %v, want %v", name, x, y, gray.GrayAt(x, y), want)
+				}
+				r, g, bl, a := c.RGBA()
+				i := 4 * ((y-b.Min.Y)*w + x - b.Min.X)
+				if got := [4]uint32(pix[i : i+4]); got != [4]uint32{r, g, bl, a} {
+					t.Fatalf("%s: premultiplied at %d,%d = %v, want %v", name, x, y, got, [4]uint32{r, g, bl, a})
+				}
+			}
+		}
+	}
+}
+
+func TestParallelResultsMatchSerial(t *testing.T) {
+	defer func(n int) { maxWorkers = n }(maxWorkers)
+	img := randomImages(70, 50)["nrgba"]
+	for _, op := range operations {
+		maxWorkers = 1
+		serial, err := applyOperation(img, op.Name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		maxWorkers = 4
+		parallel, _ := applyOperation(img, op.Name, nil)
+		b := serial.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if serial.At(x, y) != parallel.At(x, y) {
+					t.Fatalf("%s: pixel %d,%d differs between serial and parallel runs", op.Name, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestBinarizersRespectBounds(t *testing.T) {
+	images := randomImages(40, 30)
+	origin, offset := images["nrgba"], images["offset"]
+	filters := map[string]func(image.Image) image.Image{
+		"manual":  func(m image.Image) image.Image { return binarizeManual(m, 100) },
+		"percent": func(m image.Image) image.Image { return binalizePercentBlack(m, 40) },
+		"mean":    func(m image.Image) image.Image { return binalizeMeanIterative(m, 50) },
+		"otsu":    binarizeOtsu,
+		"niblack": func(m image.Image) image.Image { return binarizeNiblack(m, 7, -0.2) },
+		"bernsen": func(m image.Image) image.Image { return binarizeBernsen(m, 7, 15) },
+		"dilate":  dilation,
+		"erode":   erosion,
+	}
+	for name, filter := range filters {
+		want, got := filter(origin).(*image.Gray), filter(offset).(*image.Gray)
+		if got.Bounds() != offset.Bounds() {
+			t.Errorf("%s: bounds %v, want %v", name, got.Bounds(), offset.Bounds())
+			continue
+		}
+		for i := range want.Pix {
+			if want.Pix[i] != got.Pix[i] {
+				t.Errorf("%s: result depends on the position of the bounds", name)
+				break
+			}
+		}
+	}
+}
@@ -1,12 +1,9 @@
 package main
 
 import (
-	"bytes"
-	"encoding/base64"
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -20,18 +17,26 @@ func pixelGreen(r, g, b uint32, threshold uint8) bool {
 }
 
 func (a *App) HandleGrassTask(base64img string, threshold uint8) string {
-	m, err := decodeBasePngToImg(base64img, a.ctx)
+	m, err := a.imageFromRef(base64img)
 	if err != nil {
 		return ""
 	}
 
-	m, binary, percent := binarizeOtsuForBfsWithGreenPercentCalculation(m, threshold)
+	coloredM, percent := grassImage(m, threshold)
 	runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
 		Type:          runtime.InfoDialog,
 		Title:         "Green percentage on the image",
 		Message:       fmt.Sprintf("Green percentage on the image is: %f%%", percent),
 		DefaultButton: "Ok",
 	})
+	return a.refFromImage(base64img, coloredM)
+}
+
+// grassImage paints the largest dark region of the Otsu binarisation red on
+// that binarisation and returns the percentage of green pixels, those whose
+// green is at least threshold above red and blue.
+func grassImage(m image.Image, threshold uint8) (image.Image, float64) {
+	m, binary, percent := binarizeOtsuForBfsWithGreenPercentCalculation(m, threshold)
 	largestGroup := findLargestGroup(binary)
 
 	coloredM := image.NewRGBA(m.Bounds())
@@ -43,13 +48,7 @@ func (a *App) HandleGrassTask(base64img string, threshold uint8) string {
 	for _, p := range largestGroup {
 		coloredM.Set(p.X, p.Y, color.RGBA{255, 0, 0, 255})
 	}
-
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, coloredM); err != nil {
-		return ""
-	}
-	base64img = base64.StdEncoding.EncodeToString(buf.Bytes())
-	return fmt.Sprintf("data:image/png;base64,%s", base64img)
+	return coloredM, percent
 }
 
 func binarizeOtsuForBfsWithGreenPercentCalculation(m image.Image, greenThreshold uint8) (image.Image, [][]bool, float64) {
@@ -11,6 +11,7 @@ import (
 	"image/png"
 	"math"
 	"strconv"
+	"strings"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -24,58 +25,40 @@ const (
 	division
 )
 
+// pointWiseRgbValues holds one operand per channel: the value added or
+// subtracted, or the factor multiplied or divided by.
 type pointWiseRgbValues struct {
-	r  uint8
-	g  uint8
-	b  uint8
+	r  float64
+	g  float64
+	b  float64
 	op operation
 }
 
-func (a *App) HandleToGrayPointWiseTransformations(methodType string, base64str string) string {
-	m, err := decodeBasePngToImg(base64str, a.ctx)
-	if err != nil || m == nil {
-		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
-			Type:          runtime.InfoDialog,
-			Title:         "Could not proceed with the operation",
-			Message:       fmt.Sprintf("Couldn't decode the image sry: %s", err.Error()),
-			DefaultButton: "Ok",
-		})
-	}
-	bounds := m.Bounds()
-	newM := image.NewGray(bounds)
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r16, g16, b16, _ := m.At(x, y).RGBA()
-			r8 := uint8(r16 >> 8)
-			g8 := uint8(g16 >> 8)
-			b8 := uint8(b16 >> 8)
-			var grayVal uint8
-			switch methodType {
-			case "average":
-				grayVal = r8/3 + g8/3 + b8/3
-			case "weights":
-				grayVal = uint8(0.299*float64(r8) + 0.587*float64(g8) + 0.114*float64(b8))
-			default:
-				runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
-					Type:          runtime.InfoDialog,
-					Title:         "Invalid method type",
-					Message:       "Converting to gray scale can be used only with 'average' or 'weights' type",
-					DefaultButton: "Ok",
-				})
-				return ""
-			}
+var errDivisionByZero = errors.New("cannot divide by zero")
+
+// apply runs the operation on one 0-255 channel value.
+func (pwrv pointWiseRgbValues) apply(v, operand float64) float64 {
+	switch pwrv.op {
+	case addition:
+		return v + operand
+	case substraction:
+		return v - operand
+	case multiplication:
+		return v * operand
+	case division:
+		return v / operand
+	}
+	return v
+}
 
-			grayCol := color.Gray{Y: grayVal}
-			newM.Set(x, y, grayCol)
-		}
-	}
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newM); err != nil {
-		return ""
+// HandleToGrayPointWiseTransformations converts to gray with one of the
+// methods of HandleGrayscale that take no parameters; "weights" is Rec.601.
+func (a *App) HandleToGrayPointWiseTransformations(methodType string, base64str string) string {
+	method := GrayMethod(methodType)
+	if methodType == "weights" {
+		method = grayRec601
 	}
-	base64str = base64.StdEncoding.EncodeToString(buf.Bytes())
-	dataUrl := fmt.Sprintf("data:image/png;base64,%s", base64str)
-	return dataUrl
+	return a.HandleGrayscale(base64str, GrayOptions{Method: method})
 }
 
 func decodeBasePngToImg(base64str string, ctx context.Context) (image.Image, error) {
@@ -96,7 +79,7 @@ func decodeBasePngToImg(base64str string, ctx context.Context) (image.Image, err
 
 func (a *App) HandleAlphaPointWiseTransformations(alphaVal uint8, base64str string) string {
 	var newBase64str string
-	m, err := decodeBasePngToImg(base64str, a.ctx)
+	m, err := a.imageFromRef(base64str)
 	if err != nil {
 		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
 			Type:          runtime.InfoDialog,
@@ -106,7 +89,7 @@ func (a *App) HandleAlphaPointWiseTransformations(alphaVal uint8, base64str stri
 		})
 		return ""
 	}
-	newBase64str = generateNewAlphaBase64Str(alphaVal, m)
+	newBase64str = a.refFromImage(base64str, newAlphaImage(alphaVal, m))
 	if newBase64str == "" {
 		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
 			Type:          runtime.InfoDialog,
@@ -118,10 +101,7 @@ func (a *App) HandleAlphaPointWiseTransformations(alphaVal uint8, base64str stri
 	return newBase64str
 }
 
-func generateNewAlphaBase64Str(a8 uint8, m image.Image) string {
-	if m == nil {
-		return ""
-	}
+func newAlphaImage(a8 uint8, m image.Image) image.Image {
 	// From stdlib example, trying this out because positions might not start from 0
 	// although in example they do so idk/idc
 	bounds := m.Bounds()
@@ -135,14 +115,7 @@ func generateNewAlphaBase64Str(a8 uint8, m image.Image) string {
 			newM.Set(x, y, color.RGBA{r8, g8, b8, a8})
 		}
 	}
-
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newM); err != nil {
-		return ""
-	}
-	base64str := base64.StdEncoding.EncodeToString(buf.Bytes())
-	dataUrl := fmt.Sprintf("data:image/png;base64,%s", base64str)
-	return dataUrl
+	return newM
 }
 
 func (a *App) HandleRgbPointWiseTransformations(values []string, base64str string) string {
@@ -157,8 +130,10 @@ func (a *App) HandleRgbPointWiseTransformations(values []string, base64str strin
 		})
 		return newBase64str
 	}
-	m, err := decodeBasePngToImg(base64str, a.ctx)
-	newBase64str = generateNewRgbBase64Str(*pwrv, m)
+	m, err := a.imageFromRef(base64str)
+	if err == nil {
+		newBase64str = a.refFromImage(base64str, newRgbImage(*pwrv, m))
+	}
 	if newBase64str == "" {
 		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
 			Type:          runtime.InfoDialog,
@@ -170,47 +145,38 @@ func (a *App) HandleRgbPointWiseTransformations(values []string, base64str strin
 	return newBase64str
 }
 
-func generateNewRgbBase64Str(pwrv pointWiseRgbValues, img image.Image) string {
-	if img == nil {
-		return ""
-	}
-	newImg := image.NewRGBA(img.Bounds())
-	for y := 0; y < img.Bounds().Dy(); y++ {
-		for x := 0; x < img.Bounds().Dx(); x++ {
-			clr := img.At(x, y)
-			r16, g16, b16, a16 := clr.RGBA()
-			r8 := uint8(r16 >> 8)
-			g8 := uint8(g16 >> 8)
-			b8 := uint8(b16 >> 8)
-			a8 := uint8(a16 >> 8)
-			switch pwrv.op {
-			case addition:
-				r8 = uint8(math.Min(float64(r8+pwrv.r), 255))
-				g8 = uint8(math.Min(float64(g8+pwrv.r), 255))
-				b8 = uint8(math.Min(float64(b8+pwrv.r), 255))
-			case substraction:
-				r8 = uint8(math.Max(float64(r8-pwrv.r), 0))
-				g8 = uint8(math.Max(float64(g8-pwrv.r), 0))
-				b8 = uint8(math.Max(float64(b8-pwrv.r), 0))
-			case division:
-				r8 = uint8(math.Max(float64(r8/pwrv.r), 0))
-				g8 = uint8(math.Max(float64(g8/pwrv.r), 0))
-				b8 = uint8(math.Max(float64(b8/pwrv.r), 0))
-			case multiplication:
-				r8 = uint8(math.Min(float64(r8*pwrv.r), 255))
-				g8 = uint8(math.Min(float64(g8*pwrv.r), 255))
-				b8 = uint8(math.Min(float64(b8*pwrv.r), 255))
+// newRgbImage applies the operation to every colour channel with its own
+// operand and saturates the result to 0-255. Alpha is kept, the channels are
+// taken without premultiplication so translucent pixels are not darkened.
+func newRgbImage(pwrv pointWiseRgbValues, img image.Image) image.Image {
+	src := toNRGBA(img)
+	b := img.Bounds()
+	operands := [3]float64{pwrv.r, pwrv.g, pwrv.b}
+	newImg := image.NewNRGBA(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			dst := newImg.Pix[y*newImg.Stride:]
+			for x := 0; x < b.Dx(); x++ {
+				for c, operand := range operands {
+					dst[4*x+c] = clampUint8(pwrv.apply(float64(row[4*x+c]), operand))
+				}
+				dst[4*x+3] = row[4*x+3]
 			}
-			newImg.Set(x, y, color.RGBA{r8, g8, b8, a8})
+		}
+	})
+	return newImg
+}
+
+var operationNames = []string{"addition", "substraction", "multiplication", "division"}
+
+func parseOperation(name string) (operation, error) {
+	for i, n := range operationNames {
+		if n == name {
+			return operation(i), nil
 		}
 	}
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, newImg); err != nil {
-		return ""
-	}
-	base64str := base64.StdEncoding.EncodeToString(buf.Bytes())
-	dataUrl := fmt.Sprintf("data:image/png;base64,%s", base64str)
-	return dataUrl
+	return 0, errors.New("Unknown operation for rgb")
 }
 
 func parseRgb(values []string) (*pointWiseRgbValues, error) {
@@ -218,52 +184,40 @@ func parseRgb(values []string) (*pointWiseRgbValues, error) {
 		return nil, errors.New("Operation require 4 elements")
 	}
 	valuesStruct := new(pointWiseRgbValues)
-	switch values[0] {
-	case "addition":
-		valuesStruct.op = addition
-	case "substraction":
-		valuesStruct.op = substraction
-	case "multiplication":
-		valuesStruct.op = multiplication
-	case "division":
-		valuesStruct.op = division
-	default:
-		return nil, errors.New("Unknown operation for rgb")
-	}
-
-	r, err := strconv.Atoi(values[1])
-	if err != nil {
-		return nil, errors.New("Problem with converting integer")
-	}
-	if iLimitExceed(r) {
-		return nil, errors.New("r must be value between 0 and 255")
-	}
-	valuesStruct.r = uint8(r)
-
-	g, err := strconv.Atoi(values[2])
+	op, err := parseOperation(values[0])
 	if err != nil {
-		return nil, errors.New("Problem with converting integer")
-	}
-	if iLimitExceed(g) {
-		return nil, errors.New("g must be value between 0 and 255")
+		return nil, err
 	}
-	valuesStruct.g = uint8(g)
+	valuesStruct.op = op
 
-	b, err := strconv.Atoi(values[3])
-	if err != nil {
-		return nil, errors.New("Problem with converting integer")
+	// additions and subtractions take a level, multiplications and
+	// divisions a factor; both may have a fraction
+	operands := []*float64{&valuesStruct.r, &valuesStruct.g, &valuesStruct.b}
+	for i, operand := range operands {
+		v, err := strconv.ParseFloat(strings.TrimSpace(values[i+1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number, got '%s'", "rgb"[i:i+1], values[i+1])
+		}
+		*operand = v
 	}
-	if iLimitExceed(b) {
-		return nil, errors.New("b must be value between 0 and 255")
+	if err := valuesStruct.validate(); err != nil {
+		return nil, err
 	}
-	valuesStruct.b = uint8(b)
-
 	return valuesStruct, nil
 }
 
-func iLimitExceed(v int) bool {
-	if v < 0 || v > 255 {
-		return true
+func (pwrv pointWiseRgbValues) validate() error {
+	for i, v := range [3]float64{pwrv.r, pwrv.g, pwrv.b} {
+		if fLimitExceed(v) {
+			return fmt.Errorf("%s must be value between 0 and 255", "rgb"[i:i+1])
+		}
+		if v == 0 && pwrv.op == division {
+			return fmt.Errorf("%s: %w", "rgb"[i:i+1], errDivisionByZero)
+		}
 	}
-	return false
+	return nil
+}
+
+func fLimitExceed(v float64) bool {
+	return math.IsNaN(v) || v < 0 || v > 255
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNewRgbImagePerChannel(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{100, 200, 250, 128})
+	tests := []struct {
+		op      string
+		r, g, b string
+		want    color.NRGBA
+	}{
+		{"addition", "10", "60", "20", color.NRGBA{110, 255, 255, 128}},
+		{"substraction", "120", "0.4", "50", color.NRGBA{0, 200, 200, 128}},
+		{"multiplication", "1.5", "0.5", "2", color.NRGBA{150, 100, 255, 128}},
+		{"division", "2", "0.5", "3", color.NRGBA{50, 255, 83, 128}},
+	}
+	for _, tt := range tests {
+		pwrv, err := parseRgb([]string{tt.op, tt.r, tt.g, tt.b})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.op, err)
+		}
+		got := newRgbImage(*pwrv, img).(*image.NRGBA).NRGBAAt(0, 0)
+		if got != tt.want {
+			t.Errorf("%s %s %s %s: got %v, want %v", tt.op, tt.r, tt.g, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseRgbRejects(t *testing.T) {
+	invalid := [][]string{
+		{"addition", "1", "2"},
+		{"power", "1", "2", "3"},
+		{"addition", "1", "x", "3"},
+		{"addition", "1", "256", "3"},
+		{"multiplication", "-1", "1", "1"},
+		{"division", "1", "NaN", "1"},
+	}
+	for _, values := range invalid {
+		if _, err := parseRgb(values); err == nil {
+			t.Errorf("%v should be rejected", values)
+		}
+	}
+	if _, err := parseRgb([]string{"division", "1", "0", "2"}); !errors.Is(err, errDivisionByZero) {
+		t.Errorf("dividing by zero should fail with errDivisionByZero, got %v", err)
+	}
+	if _, err := parseRgb([]string{"multiplication", "0", "0", "0"}); err != nil {
+		t.Errorf("multiplying by zero is fine, got %v", err)
+	}
+}
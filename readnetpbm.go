@@ -671,8 +671,9 @@ func parseNetPbm(file *os.File) (image.Image, []string, error) {
 
 	if scanner.Scan() {
 		line := scanner.Text()
-		fields := strings.Fields(line)
-		magicNum = fields[0]
+		if fields := strings.Fields(line); len(fields) > 0 {
+			magicNum = fields[0]
+		}
 	}
 	if _, err := file.Seek(0, 0); err != nil {
 		return nil, nil, fmt.Errorf("problem setting offset back: +%v", err)
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	recentFilesLimit = 10
+	// fileChangedEvent is emitted with the path of an opened file that was
+	// modified on disk by another program
+	fileChangedEvent  = "file:changed"
+	watchPollInterval = 2 * time.Second
+)
+
+// recentFiles is the most-recently-used list of imported files, persisted in
+// the user config directory between runs.
+type recentFiles struct {
+	lock  sync.Mutex
+	path  string
+	files []string
+}
+
+func newRecentFiles() *recentFiles {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Println("recent files will not be persisted:", err)
+		return &recentFiles{}
+	}
+	return loadRecentFiles(filepath.Join(dir, "computer_graphics_app", "recent.json"))
+}
+
+// loadRecentFiles reads the list saved at path, a missing file is an empty
+// list.
+func loadRecentFiles(path string) *recentFiles {
+	r := &recentFiles{path: path}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Println("could not read recent files:", err)
+		}
+		return r
+	}
+	if err := json.Unmarshal(data, &r.files); err != nil {
+		log.Println("could not parse recent files:", err)
+	}
+	return r
+}
+
+// add moves path to the front of the list and saves it.
+func (r *recentFiles) add(path string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.files = slices.DeleteFunc(r.files, func(f string) bool { return f == path })
+	r.files = append([]string{path}, r.files...)
+	if len(r.files) > recentFilesLimit {
+		r.files = r.files[:recentFilesLimit]
+	}
+	if r.path == "" {
+		return
+	}
+	data, err := json.Marshal(r.files)
+	if err != nil {
+		log.Println("could not encode recent files:", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		log.Println("could not save recent files:", err)
+		return
+	}
+	if err := writeFileAtomic(r.path, data); err != nil {
+		log.Println("could not save recent files:", err)
+	}
+}
+
+func (r *recentFiles) list() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return slices.Clone(r.files)
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// fileWatcher polls opened source files and reports the ones whose size or
+// modification time changed since the last import.
+type fileWatcher struct {
+	lock    sync.Mutex
+	watched map[string]fileStamp
+}
+
+func newFileWatcher() *fileWatcher {
+	return &fileWatcher{watched: make(map[string]fileStamp)}
+}
+
+func (fw *fileWatcher) watch(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+	fw.watched[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+func (fw *fileWatcher) unwatch(path string) {
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+	delete(fw.watched, path)
+}
+
+// changed returns the files modified since the last poll and remembers their
+// new stamp, so each change is reported once.
+func (fw *fileWatcher) changed() []string {
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+	var changed []string
+	for path, stamp := range fw.watched {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		current := fileStamp{modTime: info.ModTime(), size: info.Size()}
+		if !current.modTime.Equal(stamp.modTime) || current.size != stamp.size {
+			fw.watched[path] = current
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+func (w *Worker) pollWatchedFiles(ctx context.Context) {
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, path := range w.watcher.changed() {
+			runtime.EventsEmit(ctx, fileChangedEvent, path)
+		}
+	}
+}
+
+// RecentFiles returns recently imported files, newest first.
+func (w *Worker) RecentFiles() []string {
+	return w.recent.list()
+}
+
+// UnwatchFile stops reporting changes of a file, e.g. when the user declined
+// to reload it.
+func (w *Worker) UnwatchFile(path string) {
+	w.watcher.unwatch(path)
+}
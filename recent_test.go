@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecentFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "recent.json")
+	r := loadRecentFiles(path)
+	if files := r.list(); len(files) != 0 {
+		t.Fatalf("a missing file should give an empty list, got %v", files)
+	}
+	r.add("a.png")
+	r.add("b.png")
+	r.add("a.png")
+	if files := r.list(); !slices.Equal(files, []string{"a.png", "b.png"}) {
+		t.Errorf("reopened files should move to the front once, got %v", files)
+	}
+
+	for i := 0; i < recentFilesLimit+5; i++ {
+		r.add(fmt.Sprintf("%d.png", i))
+	}
+	files := r.list()
+	if len(files) != recentFilesLimit || files[0] != fmt.Sprintf("%d.png", recentFilesLimit+4) {
+		t.Errorf("expected the newest %d files, got %v", recentFilesLimit, files)
+	}
+
+	if reloaded := loadRecentFiles(path).list(); !slices.Equal(reloaded, files) {
+		t.Errorf("saved list came back as %v, want %v", reloaded, files)
+	}
+}
+
+func TestFileWatcherChanged(t *testing.T) {
+	dir := t.TempDir()
+	watched, ignored := filepath.Join(dir, "watched.png"), filepath.Join(dir, "ignored.png")
+	for _, path := range []string{watched, ignored} {
+		if err := os.WriteFile(path, []byte("one"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fw := newFileWatcher()
+	fw.watch(watched)
+	if changed := fw.changed(); len(changed) != 0 {
+		t.Fatalf("nothing changed yet, got %v", changed)
+	}
+
+	later := time.Now().Add(time.Minute)
+	for _, path := range []string{watched, ignored} {
+		if err := os.WriteFile(path, []byte("two!"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if changed := fw.changed(); !slices.Equal(changed, []string{watched}) {
+		t.Errorf("expected only the watched file, got %v", changed)
+	}
+	if changed := fw.changed(); len(changed) != 0 {
+		t.Errorf("a change should be reported once, got %v", changed)
+	}
+
+	fw.unwatch(watched)
+	if err := os.WriteFile(watched, []byte("three"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed := fw.changed(); len(changed) != 0 {
+		t.Errorf("unwatched files should be ignored, got %v", changed)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// imageRefPrefix is the asset server path images from the registry are served
+// under. A reference like "/images/<id>" can be used directly as an <img> src
+// on the frontend and passed back to any Handle* method instead of a data URL.
+const imageRefPrefix = "/images/"
+
+var errImageNotFound = errors.New("image not found in the registry")
+
+// imageRegistry keeps decoded images on the Go side so operations do not have
+// to round-trip base64 PNGs through the webview.
+type imageRegistry struct {
+	lock   sync.RWMutex
+	images map[string]image.Image
+}
+
+func newImageRegistry() *imageRegistry {
+	return &imageRegistry{images: make(map[string]image.Image)}
+}
+
+// put stores m and returns its reference.
+func (r *imageRegistry) put(m image.Image) string {
+	id := uuid.New().String()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.images[id] = m
+	return imageRefPrefix + id
+}
+
+func (r *imageRegistry) get(ref string) (image.Image, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	m, ok := r.images[strings.TrimPrefix(ref, imageRefPrefix)]
+	if !ok {
+		return nil, errImageNotFound
+	}
+	return m, nil
+}
+
+func (r *imageRegistry) release(ref string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.images, strings.TrimPrefix(ref, imageRefPrefix))
+}
+
+// ServeHTTP serves registry images as PNG to the webview. Compression is kept
+// low as the bytes never leave the machine.
+func (r *imageRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.URL.Path, imageRefPrefix) {
+		http.NotFound(w, req)
+		return
+	}
+	m, err := r.get(req.URL.Path)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(&buf, m); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	// Images in the registry never change, each operation makes a new one
+	w.Header().Set("Cache-Control", "max-age=31536000, immutable")
+	w.Write(buf.Bytes())
+}
+
+func isImageRef(ref string) bool {
+	return strings.HasPrefix(ref, imageRefPrefix)
+}
+
+// imageFromRef resolves either a registry reference or a base64 PNG data URL.
+func (a *App) imageFromRef(ref string) (image.Image, error) {
+	if isImageRef(ref) {
+		return a.images.get(ref)
+	}
+	return decodeBasePngToImg(ref, a.ctx)
+}
+
+// refFromImage returns the result in the same form the input came in, a new
+// registry reference for references and a PNG data URL otherwise. An empty
+// string means the image could not be encoded. With an active selection the
+// result only replaces the input inside the mask.
+func (a *App) refFromImage(ref string, m image.Image) string {
+	return a.encodeLike(ref, a.applySelection(ref, m))
+}
+
+// encodeLike is refFromImage without the selection, for images derived from
+// the input that do not replace it.
+func (a *App) encodeLike(ref string, m image.Image) string {
+	if isImageRef(ref) {
+		return a.images.put(m)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		return ""
+	}
+	base64str := base64.StdEncoding.EncodeToString(buf.Bytes())
+	return fmt.Sprintf("data:image/png;base64,%s", base64str)
+}
+
+// applyToImage decodes the image, runs apply on it and returns the result
+// like refFromImage. Decoding problems are shown in a dialog and give "".
+func (a *App) applyToImage(base64str string, apply func(image.Image) image.Image) string {
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	return a.refFromImage(base64str, apply(m))
+}
+
+// showInvalidParameters reports options that failed validation.
+func (a *App) showInvalidParameters(err error) {
+	runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+		Type:          runtime.InfoDialog,
+		Title:         "Invalid parameters",
+		Message:       err.Error(),
+		DefaultButton: "Ok",
+	})
+}
+
+// decodeDataURL decodes a base64 data URL of any registered image format
+// without showing dialogs, for callers that report errors themselves.
+func decodeDataURL(dataURL string) (image.Image, error) {
+	_, data, ok := strings.Cut(dataURL, ",")
+	if !ok {
+		return nil, errors.New("invalid data URI format")
+	}
+	imgBytes, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	m, _, err := image.Decode(bytes.NewReader(imgBytes))
+	return m, err
+}
+
+// RegisterImage moves a canvas data URL into the registry and returns its
+// reference, so following operations can work on references only.
+func (a *App) RegisterImage(base64str string) (string, error) {
+	m, err := decodeDataURL(base64str)
+	if err != nil {
+		return "", err
+	}
+	return a.images.put(m), nil
+}
+
+// ReleaseImage drops an image the frontend no longer displays.
+func (a *App) ReleaseImage(ref string) {
+	a.images.release(ref)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+type SharpenMethod string
+
+const (
+	sharpenUnsharp  SharpenMethod = "unsharp"
+	sharpenHighPass SharpenMethod = "highpass"
+)
+
+var AllSharpenMethods = []struct {
+	Value  SharpenMethod
+	TSName string
+}{
+	{sharpenUnsharp, "unsharp"},
+	{sharpenHighPass, "highpass"},
+}
+
+// SharpenOptions configures HandleSharpen. Radius is the sigma of the blur the
+// detail is measured against. Differences smaller than Threshold are left
+// alone so flat areas and noise are not sharpened. With Luminance set only
+// the brightness is sharpened, which avoids colour fringes along edges.
+type SharpenOptions struct {
+	Method    SharpenMethod `json:"method"`
+	Amount    float64       `json:"amount"`
+	Radius    float64       `json:"radius"`
+	Threshold float64       `json:"threshold"`
+	Luminance bool          `json:"luminance"`
+}
+
+func (o SharpenOptions) validate() error {
+	if o.Method != sharpenUnsharp && o.Method != sharpenHighPass {
+		return fmt.Errorf("unknown sharpening method '%s'", o.Method)
+	}
+	if o.Amount < 0 {
+		return fmt.Errorf("amount must not be negative, got %g", o.Amount)
+	}
+	if o.Radius <= 0 || o.Radius*3 > maxGaussianRadius {
+		return fmt.Errorf("radius must be between 0 and %d, got %g", maxGaussianRadius/3, o.Radius)
+	}
+	if o.Threshold < 0 || o.Threshold > 255 {
+		return fmt.Errorf("threshold must be between 0 and 255, got %g", o.Threshold)
+	}
+	return nil
+}
+
+// apply sharpens one value in [0, 255] given its detail, the difference to
+// the blurred value.
+func (o SharpenOptions) apply(v, detail float64) float64 {
+	if math.Abs(detail) < o.Threshold {
+		return v
+	}
+	if o.Method == sharpenHighPass {
+		// overlay of the grey-centred high-pass layer over the original
+		base := v / 255
+		layer := min(max(0.5+o.Amount*detail/255, 0), 1)
+		if base < 0.5 {
+			return 255 * 2 * base * layer
+		}
+		return 255 * (1 - 2*(1-base)*(1-layer))
+	}
+	return v + o.Amount*detail
+}
+
+func luma(r, g, b float64) float64 {
+	return 0.299*r + 0.587*g + 0.114*b
+}
+
+// sharpen compares every pixel with its Gaussian blur and amplifies the
+// difference. Alpha is kept.
+func sharpen(img image.Image, opts SharpenOptions) image.Image {
+	b := img.Bounds()
+	src := toNRGBA(img)
+	blurred := gaussianBlur(img, opts.Radius, 0).(*image.NRGBA)
+	result := image.NewNRGBA(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			blurRow := blurred.Pix[y*blurred.Stride:]
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < b.Dx(); x++ {
+				px, bp := row[4*x:4*x+4], blurRow[4*x:4*x+4]
+				r, g, bl := float64(px[0]), float64(px[1]), float64(px[2])
+				if opts.Luminance {
+					l := luma(r, g, bl)
+					shift := opts.apply(l, l-luma(float64(bp[0]), float64(bp[1]), float64(bp[2]))) - l
+					r, g, bl = r+shift, g+shift, bl+shift
+				} else {
+					r = opts.apply(r, r-float64(bp[0]))
+					g = opts.apply(g, g-float64(bp[1]))
+					bl = opts.apply(bl, bl-float64(bp[2]))
+				}
+				dst[4*x], dst[4*x+1], dst[4*x+2], dst[4*x+3] = clampUint8(r), clampUint8(g), clampUint8(bl), px[3]
+			}
+		}
+	})
+	return result
+}
+
+func (a *App) HandleSharpen(base64str string, opts SharpenOptions) string {
+	if err := opts.validate(); err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Invalid parameters",
+			Message:       err.Error(),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	m, err := a.imageFromRef(base64str)
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.InfoDialog,
+			Title:         "Decoding problem",
+			Message:       fmt.Sprintf("Image could not be decoded: %s", err.Error()),
+			DefaultButton: "Ok",
+		})
+		return ""
+	}
+	return a.refFromImage(base64str, sharpen(m, opts))
+}
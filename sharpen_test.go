@@ -0,0 +1,40 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSharpenIncreasesEdgeContrast(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			v := uint8(100)
+			if x >= 5 {
+				v = 150
+			}
+			img.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	for _, method := range []SharpenMethod{sharpenUnsharp, sharpenHighPass} {
+		for _, luminance := range []bool{false, true} {
+			out := sharpen(img, SharpenOptions{Method: method, Amount: 1, Radius: 1, Luminance: luminance})
+			dark, _, _, _ := out.At(4, 5).RGBA()
+			light, _, _, _ := out.At(5, 5).RGBA()
+			flat, _, _, _ := out.At(0, 5).RGBA()
+			if dark>>8 >= 100 || light>>8 <= 150 {
+				t.Errorf("%s luminance=%v: edge %d/%d was not sharpened", method, luminance, dark>>8, light>>8)
+			}
+			if flat>>8 != 100 {
+				t.Errorf("%s luminance=%v: flat area changed to %d", method, luminance, flat>>8)
+			}
+		}
+	}
+
+	// a threshold above the edge step leaves the image untouched
+	out := sharpen(img, SharpenOptions{Method: sharpenUnsharp, Amount: 1, Radius: 1, Threshold: 60})
+	if v, _, _, _ := out.At(4, 5).RGBA(); v>>8 != 100 {
+		t.Errorf("detail below threshold was sharpened to %d", v>>8)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Conduction is the edge-stopping function of the anisotropic diffusion.
+// Exponential favours high-contrast edges, quadratic favours wide regions.
+type Conduction string
+
+const (
+	conductionExponential Conduction = "exponential"
+	conductionQuadratic   Conduction = "quadratic"
+)
+
+var AllConductions = []struct {
+	Value  Conduction
+	TSName string
+}{
+	{conductionExponential, "exponential"},
+	{conductionQuadratic, "quadratic"},
+}
+
+const (
+	maxBilateralSigma   = 50
+	maxKuwaharaRadius   = 20
+	maxDiffusionSteps   = 500
+	diffusionStepLambda = 0.25 // largest stable step for 4 neighbours
+)
+
+// Defaults used when the filters are picked from the filter dialog, and the
+// defaults of their operations.
+const (
+	defaultBilateralSpatial = 3
+	defaultBilateralRange   = 25
+	defaultKuwaharaRadius   = 2
+	defaultDiffusionSteps   = 15
+	defaultDiffusionKappa   = 20
+)
+
+// nrgbaPlanes splits an image into straight-alpha float channels.
+func nrgbaPlanes(img image.Image) (planes [4][]float64, w, h int) {
+	b := img.Bounds()
+	w, h = b.Dx(), b.Dy()
+	for c := range planes {
+		planes[c] = make([]float64, w*h)
+	}
+	src := toNRGBA(img)
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				i := y*w + x
+				for c := range planes {
+					planes[c][i] = float64(row[4*x+c])
+				}
+			}
+		}
+	})
+	return planes, w, h
+}
+
+func nrgbaFromPlanes(planes [4][]float64, bounds image.Rectangle) *image.NRGBA {
+	result := image.NewNRGBA(bounds)
+	w := bounds.Dx()
+	parallelRows(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < w; x++ {
+				for c := range planes {
+					dst[4*x+c] = clampUint8(planes[c][y*w+x])
+				}
+			}
+		}
+	})
+	return result
+}
+
+// bilateralFilter averages neighbours weighted both by distance and by colour
+// difference, so pixels across an edge barely contribute. The colour
+// difference is the RGB distance, which keeps the channels from drifting
+// apart. Alpha is kept and neighbours are also weighted by their alpha, so
+// transparent ones add no colour.
+func bilateralFilter(img image.Image, spatialSigma, rangeSigma float64) image.Image {
+	planes, w, h := nrgbaPlanes(img)
+	radius := int(math.Ceil(2 * spatialSigma))
+	spatial := make([]float64, (2*radius+1)*(2*radius+1))
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			spatial[(dy+radius)*(2*radius+1)+dx+radius] =
+				math.Exp(-float64(dx*dx+dy*dy) / (2 * spatialSigma * spatialSigma))
+		}
+	}
+	// range weights indexed by the squared RGB distance
+	rangeWeights := make([]float64, 3*255*255+1)
+	for d := range rangeWeights {
+		rangeWeights[d] = math.Exp(-float64(d) / (2 * rangeSigma * rangeSigma))
+	}
+
+	out := [4][]float64{make([]float64, w*h), make([]float64, w*h), make([]float64, w*h), planes[3]}
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				i := y*w + x
+				var sumR, sumG, sumB, sumW float64
+				for dy := -radius; dy <= radius; dy++ {
+					sy := min(max(y+dy, 0), h-1)
+					for dx := -radius; dx <= radius; dx++ {
+						j := sy*w + min(max(x+dx, 0), w-1)
+						dr, dg, db := planes[0][j]-planes[0][i], planes[1][j]-planes[1][i], planes[2][j]-planes[2][i]
+						weight := spatial[(dy+radius)*(2*radius+1)+dx+radius] * rangeWeights[int(dr*dr+dg*dg+db*db)]
+						// the centre always counts, so sumW is never 0
+						if j != i {
+							weight *= planes[3][j] / 255
+						}
+						sumR += planes[0][j] * weight
+						sumG += planes[1][j] * weight
+						sumB += planes[2][j] * weight
+						sumW += weight
+					}
+				}
+				out[0][i], out[1][i], out[2][i] = sumR/sumW, sumG/sumW, sumB/sumW
+			}
+		}
+	})
+	return nrgbaFromPlanes(out, img.Bounds())
+}
+
+// summedArea is an integral image with a zero row and column in front, so the
+// sum over [x0, x1) x [y0, y1) needs four lookups.
+type summedArea struct {
+	w    int
+	sums []float64
+}
+
+func newSummedArea(plane []float64, w, h int) summedArea {
+	s := summedArea{w: w + 1, sums: make([]float64, (w+1)*(h+1))}
+	for y := 0; y < h; y++ {
+		var row float64
+		for x := 0; x < w; x++ {
+			row += plane[y*w+x]
+			s.sums[(y+1)*s.w+x+1] = s.sums[y*s.w+x+1] + row
+		}
+	}
+	return s
+}
+
+func (s summedArea) sum(x0, y0, x1, y1 int) float64 {
+	return s.sums[y1*s.w+x1] - s.sums[y0*s.w+x1] - s.sums[y1*s.w+x0] + s.sums[y0*s.w+x0]
+}
+
+// kuwaharaFilter replaces each pixel with the mean colour of whichever of the
+// four (radius+1)-square quadrants around it has the lowest luminance
+// variance, so the average never crosses an edge. Alpha is kept; means and
+// variances are weighted by alpha so transparent pixels add no colour.
+func kuwaharaFilter(img image.Image, radius int) image.Image {
+	planes, w, h := nrgbaPlanes(img)
+	// premultiplied colour, luminance and squared luminance, then alpha
+	var weighted [6][]float64
+	for c := range weighted {
+		weighted[c] = make([]float64, w*h)
+	}
+	for i := range planes[3] {
+		a := planes[3][i] / 255
+		lum := luma(planes[0][i], planes[1][i], planes[2][i])
+		weighted[0][i], weighted[1][i], weighted[2][i] = a*planes[0][i], a*planes[1][i], a*planes[2][i]
+		weighted[3][i], weighted[4][i], weighted[5][i] = a*lum, a*lum*lum, a
+	}
+	var sums [6]summedArea
+	for c := range sums {
+		sums[c] = newSummedArea(weighted[c], w, h)
+	}
+
+	out := [4][]float64{make([]float64, w*h), make([]float64, w*h), make([]float64, w*h), planes[3]}
+	parallelRows(h, func(top, bottom int) {
+		for y := top; y < bottom; y++ {
+			for x := 0; x < w; x++ {
+				i := y*w + x
+				bestVariance := math.Inf(1)
+				var best [4]int
+				for _, q := range [4][2]int{{-radius, -radius}, {0, -radius}, {-radius, 0}, {0, 0}} {
+					// quadrants are clipped to the image
+					x0, y0 := max(x+q[0], 0), max(y+q[1], 0)
+					x1, y1 := min(x+q[0]+radius+1, w), min(y+q[1]+radius+1, h)
+					n := sums[5].sum(x0, y0, x1, y1)
+					if n <= 0 {
+						continue
+					}
+					mean := sums[3].sum(x0, y0, x1, y1) / n
+					variance := sums[4].sum(x0, y0, x1, y1)/n - mean*mean
+					if variance < bestVariance {
+						bestVariance = variance
+						best = [4]int{x0, y0, x1, y1}
+					}
+				}
+				if math.IsInf(bestVariance, 1) {
+					// nothing visible around, keep the pixel
+					out[0][i], out[1][i], out[2][i] = planes[0][i], planes[1][i], planes[2][i]
+					continue
+				}
+				n := sums[5].sum(best[0], best[1], best[2], best[3])
+				for c := 0; c < 3; c++ {
+					out[c][i] = sums[c].sum(best[0], best[1], best[2], best[3]) / n
+				}
+			}
+		}
+	})
+	return nrgbaFromPlanes(out, img.Bounds())
+}
+
+// anisotropicDiffusion is the Perona-Malik scheme: every step lets each
+// channel flow towards its four neighbours, throttled by the conduction
+// function of the local gradient so strong edges stay put. No flow crosses
+// the image border, and flow between two pixels is scaled by the smaller of
+// their alphas so nothing flows out of transparent areas. Alpha is kept.
+func anisotropicDiffusion(img image.Image, iterations int, kappa float64, conduction Conduction) image.Image {
+	planes, w, h := nrgbaPlanes(img)
+	g := func(d float64) float64 {
+		d /= kappa
+		if conduction == conductionQuadratic {
+			return 1 / (1 + d*d)
+		}
+		return math.Exp(-d * d)
+	}
+	for c := 0; c < 3; c++ {
+		cur := planes[c]
+		next := make([]float64, len(cur))
+		conduct := func(i, j int) float64 {
+			d := cur[j] - cur[i]
+			return min(planes[3][i], planes[3][j]) / 255 * g(d) * d
+		}
+		for n := 0; n < iterations; n++ {
+			parallelRows(h, func(y0, y1 int) {
+				for y := y0; y < y1; y++ {
+					for x := 0; x < w; x++ {
+						i := y*w + x
+						var flow float64
+						if x > 0 {
+							flow += conduct(i, i-1)
+						}
+						if x < w-1 {
+							flow += conduct(i, i+1)
+						}
+						if y > 0 {
+							flow += conduct(i, i-w)
+						}
+						if y < h-1 {
+							flow += conduct(i, i+w)
+						}
+						next[i] = cur[i] + diffusionStepLambda*flow
+					}
+				}
+			})
+			cur, next = next, cur
+		}
+		planes[c] = cur
+	}
+	return nrgbaFromPlanes(planes, img.Bounds())
+}
+
+func (a *App) HandleBilateralFilter(base64str string, spatialSigma, rangeSigma float64) string {
+	if spatialSigma <= 0 || spatialSigma > maxBilateralSigma || rangeSigma <= 0 {
+		a.showInvalidParameters(fmt.Errorf(
+			"spatial sigma must be between 0 and %d and range sigma positive", maxBilateralSigma,
+		))
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return bilateralFilter(m, spatialSigma, rangeSigma)
+	})
+}
+
+func (a *App) HandleKuwaharaFilter(base64str string, radius int) string {
+	if radius < 1 || radius > maxKuwaharaRadius {
+		a.showInvalidParameters(fmt.Errorf("radius must be between 1 and %d", maxKuwaharaRadius))
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return kuwaharaFilter(m, radius)
+	})
+}
+
+func (a *App) HandleAnisotropicDiffusion(base64str string, iterations int, kappa float64, conduction Conduction) string {
+	if iterations < 1 || iterations > maxDiffusionSteps || kappa <= 0 ||
+		(conduction != conductionExponential && conduction != conductionQuadratic) {
+		a.showInvalidParameters(fmt.Errorf(
+			"iterations must be between 1 and %d, kappa positive and conduction exponential or quadratic",
+			maxDiffusionSteps,
+		))
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return anisotropicDiffusion(m, iterations, kappa, conduction)
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSmoothingPreservesEdges(t *testing.T) {
+	// a step from 50 to 200 with a little checkerboard noise on both sides
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			v := 50
+			if x >= 8 {
+				v = 200
+			}
+			if (x+y)%2 == 0 {
+				v += 4
+			}
+			img.SetNRGBA(x, y, color.NRGBA{uint8(v), uint8(v), uint8(v), 255})
+		}
+	}
+	filters := map[string]func(image.Image) image.Image{
+		"bilateral": func(m image.Image) image.Image { return bilateralFilter(m, 2, 20) },
+		"kuwahara":  func(m image.Image) image.Image { return kuwaharaFilter(m, 2) },
+		"diffusion": func(m image.Image) image.Image {
+			return anisotropicDiffusion(m, 20, 10, conductionExponential)
+		},
+	}
+	for name, filter := range filters {
+		out := filter(img)
+		left, _, _, _ := out.At(7, 8).RGBA()
+		right, _, _, _ := out.At(8, 8).RGBA()
+		if left>>8 > 60 || right>>8 < 195 {
+			t.Errorf("%s: edge blurred to %d/%d", name, left>>8, right>>8)
+		}
+		a, _, _, _ := out.At(2, 2).RGBA()
+		b, _, _, _ := out.At(3, 2).RGBA()
+		if d := int(a>>8) - int(b>>8); d > 2 || d < -2 {
+			t.Errorf("%s: noise not smoothed, neighbours %d and %d", name, a>>8, b>>8)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+type ToneTransform string
+
+const (
+	toneBrightness ToneTransform = "brightness"
+	toneContrast   ToneTransform = "contrast"
+	toneGamma      ToneTransform = "gamma"
+	toneLog        ToneTransform = "log"
+	toneExp        ToneTransform = "exp"
+	tonePower      ToneTransform = "power"
+)
+
+var AllToneTransforms = []struct {
+	Value  ToneTransform
+	TSName string
+}{
+	{toneBrightness, "brightness"},
+	{toneContrast, "contrast"},
+	{toneGamma, "gamma"},
+	{toneLog, "log"},
+	{toneExp, "exp"},
+	{tonePower, "power"},
+}
+
+type ContrastPivot string
+
+const (
+	pivotMidGrey ContrastPivot = "midgrey"
+	pivotMean    ContrastPivot = "mean"
+)
+
+var AllContrastPivots = []struct {
+	Value  ContrastPivot
+	TSName string
+}{
+	{pivotMidGrey, "midgrey"},
+	{pivotMean, "mean"},
+}
+
+// toneLimits is the range of Amount for every transform.
+var toneLimits = map[ToneTransform][2]float64{
+	toneBrightness: {-255, 255},
+	toneContrast:   {0, 10},
+	toneGamma:      {0.05, 20},
+	toneLog:        {0.01, 1000},
+	toneExp:        {0.01, 1000},
+	tonePower:      {0.05, 20},
+}
+
+const maxPowerScale = 10
+
+// ToneOptions configures HandleToneTransform. Amount depends on the
+// transform, with r the input and s the output scaled to [0, 1]:
+//   - brightness: offset added to every level, -255 to 255
+//   - contrast: factor the distance to the pivot is multiplied with
+//   - gamma: gamma correction s = r^(1/Amount), above 1 brightens
+//   - log: strength a of s = log(1 + a r) / log(1 + a)
+//   - exp: strength a of s = ((1 + a)^r - 1) / a, the inverse of log
+//   - power: exponent of the power law s = Scale * r^Amount
+//
+// Pivot is the level contrast is stretched around, mid-grey or the mean
+// luminance of the image. With Luminance set the curve is applied to the
+// luminance and every channel is shifted by the same amount, which keeps
+// the hue; otherwise every channel goes through the curve.
+type ToneOptions struct {
+	Transform ToneTransform `json:"transform"`
+	Amount    float64       `json:"amount"`
+	Scale     float64       `json:"scale"`
+	Pivot     ContrastPivot `json:"pivot"`
+	Luminance bool          `json:"luminance"`
+}
+
+func (o ToneOptions) validate() error {
+	limits, ok := toneLimits[o.Transform]
+	if !ok {
+		return fmt.Errorf("unknown tone transform '%s'", o.Transform)
+	}
+	if math.IsNaN(o.Amount) || o.Amount < limits[0] || o.Amount > limits[1] {
+		return fmt.Errorf("%s amount must be between %g and %g, got %g", o.Transform, limits[0], limits[1], o.Amount)
+	}
+	if o.Transform == tonePower && (math.IsNaN(o.Scale) || o.Scale < 0 || o.Scale > maxPowerScale) {
+		return fmt.Errorf("power-law scale must be between 0 and %d, got %g", maxPowerScale, o.Scale)
+	}
+	if o.Transform == toneContrast && o.Pivot != pivotMidGrey && o.Pivot != pivotMean {
+		return fmt.Errorf("unknown contrast pivot '%s'", o.Pivot)
+	}
+	return nil
+}
+
+// curve maps a level in [0, 255] to its new level, not yet clamped. mean is
+// the mean luminance, used as the contrast pivot.
+func (o ToneOptions) curve(v, mean float64) float64 {
+	r := v / 255
+	switch o.Transform {
+	case toneBrightness:
+		return v + o.Amount
+	case toneContrast:
+		pivot := 127.5
+		if o.Pivot == pivotMean {
+			pivot = mean
+		}
+		return pivot + (v-pivot)*o.Amount
+	case toneGamma:
+		return 255 * math.Pow(r, 1/o.Amount)
+	case toneLog:
+		return 255 * math.Log1p(o.Amount*r) / math.Log1p(o.Amount)
+	case toneExp:
+		return 255 * (math.Pow(1+o.Amount, r) - 1) / o.Amount
+	case tonePower:
+		return 255 * o.Scale * math.Pow(r, o.Amount)
+	}
+	return v
+}
+
+// lut returns the curve for all 256 levels of img.
+func (o ToneOptions) lut(img image.Image) [256]uint8 {
+	var mean float64
+	if o.Transform == toneContrast && o.Pivot == pivotMean {
+		mean = meanLuminance(img)
+	}
+	var table [256]uint8
+	for v := range table {
+		table[v] = clampUint8(o.curve(float64(v), mean))
+	}
+	return table
+}
+
+// meanLuminance is the mean luma of the visible pixels, weighted by alpha.
+func meanLuminance(img image.Image) float64 {
+	src := toNRGBA(img)
+	b := img.Bounds()
+	var sum, weight float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := src.Pix[src.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			p := row[4*x : 4*x+4]
+			a := float64(p[3])
+			sum += a * luma(float64(p[0]), float64(p[1]), float64(p[2]))
+			weight += a
+		}
+	}
+	if weight == 0 {
+		return 127.5
+	}
+	return sum / weight
+}
+
+// applyLuts maps every colour channel through its own table, alpha is kept.
+func applyLuts(img image.Image, tables [3][256]uint8) image.Image {
+	src := toNRGBA(img)
+	b := img.Bounds()
+	result := image.NewNRGBA(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < b.Dx(); x++ {
+				p, out := row[4*x:4*x+4], dst[4*x:4*x+4]
+				out[0], out[1], out[2], out[3] = tables[0][p[0]], tables[1][p[1]], tables[2][p[2]], p[3]
+			}
+		}
+	})
+	return result
+}
+
+// applyLut maps every colour channel through the table, or only the
+// luminance when luminance is set. Alpha is kept.
+func applyLut(img image.Image, table [256]uint8, luminance bool) image.Image {
+	if !luminance {
+		return applyLuts(img, [3][256]uint8{table, table, table})
+	}
+	src := toNRGBA(img)
+	b := img.Bounds()
+	result := image.NewNRGBA(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			dst := result.Pix[y*result.Stride:]
+			for x := 0; x < b.Dx(); x++ {
+				p, out := row[4*x:4*x+4], dst[4*x:4*x+4]
+				l := clampUint8(luma(float64(p[0]), float64(p[1]), float64(p[2])))
+				shift := float64(table[l]) - float64(l)
+				for c := 0; c < 3; c++ {
+					out[c] = clampUint8(float64(p[c]) + shift)
+				}
+				out[3] = p[3]
+			}
+		}
+	})
+	return result
+}
+
+func toneTransform(img image.Image, opts ToneOptions) image.Image {
+	return applyLut(img, opts.lut(img), opts.Luminance)
+}
+
+func (a *App) HandleToneTransform(base64str string, opts ToneOptions) string {
+	if err := opts.validate(); err != nil {
+		a.showInvalidParameters(err)
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return toneTransform(m, opts)
+	})
+}
+
+// ToneTransferCurve returns the output level for every input level 0-255, so
+// the frontend can preview the curve before applying it. The image is only
+// needed for contrast around the mean.
+func (a *App) ToneTransferCurve(base64str string, opts ToneOptions) ([]int, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	var m image.Image = image.NewGray(image.Rectangle{})
+	if opts.Transform == toneContrast && opts.Pivot == pivotMean {
+		var err error
+		if m, err = a.imageFromRef(base64str); err != nil {
+			return nil, err
+		}
+	}
+	table := opts.lut(m)
+	curve := make([]int, len(table))
+	for i, v := range table {
+		curve[i] = int(v)
+	}
+	return curve, nil
+}
+
+const maxPosterizeLevels = 256
+
+func invertLut() [256]uint8 {
+	var table [256]uint8
+	for v := range table {
+		table[v] = uint8(255 - v)
+	}
+	return table
+}
+
+// posterizeLut quantises every level to one of levels evenly spaced levels,
+// the darkest black and the brightest white.
+func posterizeLut(levels int) [256]uint8 {
+	var table [256]uint8
+	for v := range table {
+		q := v * levels / 256
+		table[v] = clampUint8(float64(q) * 255 / float64(levels-1))
+	}
+	return table
+}
+
+// solarizeLut inverts the levels above threshold, like film exposed to light
+// again while being developed.
+func solarizeLut(threshold uint8) [256]uint8 {
+	table := invertLut()
+	for v := 0; v <= int(threshold); v++ {
+		table[v] = uint8(v)
+	}
+	return table
+}
+
+func (a *App) HandleInvert(base64str string) string {
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return applyLut(m, invertLut(), false)
+	})
+}
+
+func (a *App) HandlePosterize(base64str string, levels int) string {
+	if levels < 2 || levels > maxPosterizeLevels {
+		a.showInvalidParameters(fmt.Errorf("levels must be between 2 and %d, got %d", maxPosterizeLevels, levels))
+		return ""
+	}
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return applyLut(m, posterizeLut(levels), false)
+	})
+}
+
+func (a *App) HandleSolarize(base64str string, threshold uint8) string {
+	return a.applyToImage(base64str, func(m image.Image) image.Image {
+		return applyLut(m, solarizeLut(threshold), false)
+	})
+}
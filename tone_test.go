@@ -0,0 +1,114 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestToneLut(t *testing.T) {
+	tests := []struct {
+		opts ToneOptions
+		want map[int]uint8
+	}{
+		{ToneOptions{Transform: toneBrightness, Amount: 30}, map[int]uint8{0: 30, 100: 130, 240: 255}},
+		{ToneOptions{Transform: toneBrightness, Amount: -30}, map[int]uint8{10: 0, 100: 70}},
+		{ToneOptions{Transform: toneContrast, Amount: 2, Pivot: pivotMidGrey}, map[int]uint8{0: 0, 100: 73, 128: 129, 200: 255}},
+		{ToneOptions{Transform: toneGamma, Amount: 2}, map[int]uint8{0: 0, 64: 128, 255: 255}},
+		{ToneOptions{Transform: toneLog, Amount: 10}, map[int]uint8{0: 0, 255: 255}},
+		{ToneOptions{Transform: toneExp, Amount: 10}, map[int]uint8{0: 0, 255: 255}},
+		{ToneOptions{Transform: tonePower, Amount: 2, Scale: 1}, map[int]uint8{0: 0, 128: 64, 255: 255}},
+		{ToneOptions{Transform: tonePower, Amount: 1, Scale: 0.5}, map[int]uint8{200: 100}},
+	}
+	img := flatImage(0)
+	for _, tt := range tests {
+		if err := tt.opts.validate(); err != nil {
+			t.Fatalf("%+v: %v", tt.opts, err)
+		}
+		table := tt.opts.lut(img)
+		for in, want := range tt.want {
+			if table[in] != want {
+				t.Errorf("%s %g: level %d maps to %d, want %d", tt.opts.Transform, tt.opts.Amount, in, table[in], want)
+			}
+		}
+	}
+}
+
+func TestLogAndExpAreInverse(t *testing.T) {
+	log := ToneOptions{Transform: toneLog, Amount: 50}.lut(nil)
+	exp := ToneOptions{Transform: toneExp, Amount: 50}.lut(nil)
+	for v := 0; v < 256; v++ {
+		if d := int(exp[log[v]]) - v; d < -8 || d > 8 {
+			t.Errorf("exp(log(%d)) = %d", v, exp[log[v]])
+		}
+	}
+	if log[64] <= 64 || exp[64] >= 64 {
+		t.Errorf("log should brighten and exp darken the shadows, got %d and %d", log[64], exp[64])
+	}
+}
+
+func TestContrastAroundMean(t *testing.T) {
+	img := flatImage(60)
+	table := ToneOptions{Transform: toneContrast, Amount: 3, Pivot: pivotMean}.lut(img)
+	if table[60] != 60 {
+		t.Errorf("the mean should stay where it is, got %d", table[60])
+	}
+	got := toneTransform(img, ToneOptions{Transform: toneContrast, Amount: 3, Pivot: pivotMean})
+	if c := got.(*image.NRGBA).NRGBAAt(5, 5); c != (color.NRGBA{60, 60, 60, 200}) {
+		t.Errorf("a flat image should not change, got %v", c)
+	}
+}
+
+func TestApplyLutLuminance(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{200, 100, 50, 128})
+	opts := ToneOptions{Transform: toneBrightness, Amount: 20}
+	perChannel := toneTransform(img, opts).(*image.NRGBA).NRGBAAt(0, 0)
+	if perChannel != (color.NRGBA{220, 120, 70, 128}) {
+		t.Errorf("per channel: got %v", perChannel)
+	}
+	opts = ToneOptions{Transform: toneGamma, Amount: 2, Luminance: true}
+	got := toneTransform(img, opts).(*image.NRGBA).NRGBAAt(0, 0)
+	dr, dg, db := int(got.R)-200, int(got.G)-100, int(got.B)-50
+	if dr <= 0 || dr != dg || dg != db || got.A != 128 {
+		t.Errorf("luminance only should shift every channel alike, got %v", got)
+	}
+}
+
+func TestToneOptionsRejects(t *testing.T) {
+	invalid := []ToneOptions{
+		{Transform: "sepia", Amount: 1},
+		{Transform: toneBrightness, Amount: 300},
+		{Transform: toneGamma, Amount: 0},
+		{Transform: toneContrast, Amount: 1, Pivot: "median"},
+		{Transform: tonePower, Amount: 1, Scale: -1},
+		{Transform: toneLog, Amount: 0},
+	}
+	for _, opts := range invalid {
+		if err := opts.validate(); err == nil {
+			t.Errorf("%+v should be rejected", opts)
+		}
+	}
+}
+
+func TestClassicLuts(t *testing.T) {
+	if invert := invertLut(); invert[0] != 255 || invert[55] != 200 || invert[255] != 0 {
+		t.Errorf("invert: got %d %d %d", invert[0], invert[55], invert[255])
+	}
+	poster := posterizeLut(4)
+	for v, want := range map[int]uint8{0: 0, 63: 0, 64: 85, 127: 85, 128: 170, 200: 255, 255: 255} {
+		if poster[v] != want {
+			t.Errorf("posterize to 4 levels: %d maps to %d, want %d", v, poster[v], want)
+		}
+	}
+	identity := ToneOptions{Transform: toneBrightness}.lut(nil)
+	if posterizeLut(maxPosterizeLevels) != identity {
+		t.Error("posterizing to 256 levels should change nothing")
+	}
+	solar := solarizeLut(128)
+	for v, want := range map[int]uint8{0: 0, 128: 128, 129: 126, 255: 0} {
+		if solar[v] != want {
+			t.Errorf("solarize above 128: %d maps to %d, want %d", v, solar[v], want)
+		}
+	}
+}